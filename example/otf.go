@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"github.com/akmalfairuz/bedrockpack/pack"
 	"github.com/sandertv/gophertunnel/minecraft"
 	"log/slog"
@@ -16,7 +17,7 @@ func main() {
 		PAT:      "",
 	}
 	otf := conf.New(log)
-	if err := otf.Start(); err != nil {
+	if err := otf.Start(context.Background()); err != nil {
 		log.Error("failed to start otf", "error", err)
 		return
 	}
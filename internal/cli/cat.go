@@ -0,0 +1,52 @@
+package cli
+
+import (
+	"flag"
+	"github.com/akmalfairuz/bedrockpack/pack"
+	"io"
+)
+
+type catFlags struct {
+	keyFlags
+}
+
+var catCommand = &Command{
+	Name:    "cat",
+	Usage:   "cat [flags] <path to resource pack> <path in resource pack>",
+	Short:   "Print a single file of the resource pack, decrypting only that file",
+	MinArgs: 2,
+	Setup: func(fs *flag.FlagSet) RunFunc {
+		f := &catFlags{}
+		f.keyFlags.register(fs, true)
+		return f.run
+	},
+}
+
+func (f *catFlags) run(ctx *Context, args []string) error {
+	key, err := f.keyFlags.resolve(nil, 0)
+	if err != nil {
+		return err
+	}
+	p, err := pack.OpenEncryptedPack(args[0], key)
+	if err != nil {
+		return err
+	}
+	defer p.Close()
+
+	file, err := p.Open(args[1])
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if ctx.JSON {
+		content, err := io.ReadAll(file)
+		if err != nil {
+			return err
+		}
+		ctx.Result(map[string]any{"path": args[1], "encrypted": p.Encrypted(args[1]), "content": content})
+		return nil
+	}
+	_, err = io.Copy(ctx.out, file)
+	return err
+}
@@ -0,0 +1,281 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/akmalfairuz/bedrockpack/pack"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Exit codes returned by Run.
+const (
+	ExitOK = iota
+	// ExitError is returned for errors that do not have a more specific exit code.
+	ExitError
+	// ExitUsage is returned when a command is invoked with invalid arguments or flags.
+	ExitUsage
+	// ExitWrongKey is returned when a pack could not be decrypted with the key given.
+	ExitWrongKey
+	// ExitCheckFailed is returned when a check performed by a command, such as verify, did not pass.
+	ExitCheckFailed
+)
+
+// Command is a subcommand of bedrockpack.
+type Command struct {
+	// Name is the name used to invoke the command.
+	Name string
+	// Usage is the synopsis of the command, without the program name, such as "decrypt <pack> <key>".
+	Usage string
+	// Short is a one-line description of the command, shown in the command list.
+	Short string
+	// Long is an optional longer description of the command, shown in the help of the command.
+	Long string
+	// MinArgs is the minimum number of positional arguments of the command.
+	MinArgs int
+	// Setup registers the flags of the command on the flag set passed and returns the function that runs the
+	// command. It is called for every run, so that the values of flags are not shared between runs.
+	Setup func(fs *flag.FlagSet) RunFunc
+}
+
+// RunFunc runs a command with the positional arguments given.
+type RunFunc func(ctx *Context, args []string) error
+
+// Context is passed to a running command. It holds the output of the command.
+type Context struct {
+	// JSON is true if the command should write machine-readable output using Result.
+	JSON   bool
+	out    io.Writer
+	errOut io.Writer
+	res    any
+}
+
+// Printf writes a human-readable message to the output. It writes nothing if JSON output was requested.
+func (ctx *Context) Printf(format string, a ...any) {
+	if !ctx.JSON {
+		_, _ = fmt.Fprintf(ctx.out, format, a...)
+	}
+}
+
+// Println writes a human-readable line to the output. It writes nothing if JSON output was requested.
+func (ctx *Context) Println(a ...any) {
+	if !ctx.JSON {
+		_, _ = fmt.Fprintln(ctx.out, a...)
+	}
+}
+
+// Progress returns the writer for progress messages that a command cannot hold back, such as those of other
+// packages. It is the error output if JSON output was requested, so that the JSON output stays valid.
+func (ctx *Context) Progress() io.Writer {
+	if ctx.JSON {
+		return ctx.errOut
+	}
+	return ctx.out
+}
+
+// Result sets the machine-readable result of the command, written as JSON when the command returns if JSON
+// output was requested.
+func (ctx *Context) Result(v any) {
+	ctx.res = v
+}
+
+// exitError is an error that results in a specific exit code.
+type exitError struct {
+	code int
+	err  error
+}
+
+// Error ...
+func (e *exitError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap ...
+func (e *exitError) Unwrap() error {
+	return e.err
+}
+
+// withExitCode returns err wrapped so that Run exits with the code given.
+func withExitCode(code int, err error) error {
+	return &exitError{code: code, err: err}
+}
+
+// Run runs the command named by the first argument with the rest of the arguments, writing to os.Stdout and
+// os.Stderr. It returns the exit code of the program.
+func Run(args []string) int {
+	return run(args, os.Stdout, os.Stderr)
+}
+
+// run runs the command named by the first argument like Run, writing to the writers given.
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		if len(args) > 1 {
+			if cmd, ok := findCommand(args[1]); ok {
+				printCommandHelp(stdout, cmd)
+				return ExitOK
+			}
+		}
+		printHelp(stdout)
+		if len(args) == 0 {
+			return ExitUsage
+		}
+		return ExitOK
+	}
+
+	cmd, ok := findCommand(args[0])
+	if !ok {
+		_, _ = fmt.Fprintf(stderr, "unknown command %q\n\n", args[0])
+		printHelp(stderr)
+		return ExitUsage
+	}
+
+	ctx := &Context{out: stdout, errOut: stderr}
+	fs, runCmd := newFlagSet(cmd)
+	fs.BoolVar(&ctx.JSON, "json", false, "write machine-readable JSON output")
+	positional, err := parseFlags(fs, args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		printCommandHelp(stdout, cmd)
+		return ExitOK
+	}
+	if err == nil && len(positional) < cmd.MinArgs {
+		err = fmt.Errorf("expected at least %d arguments, got %d", cmd.MinArgs, len(positional))
+	}
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "error: %s\n\n", err)
+		printCommandHelp(stderr, cmd)
+		return ExitUsage
+	}
+
+	if err := runCmd(ctx, positional); err != nil {
+		code := exitCode(err)
+		if ctx.JSON {
+			out := map[string]any{"error": err.Error(), "exit_code": code}
+			if ctx.res != nil {
+				out["result"] = ctx.res
+			}
+			writeJSON(stdout, out)
+		} else {
+			_, _ = fmt.Fprintf(stderr, "error: %s\n", err)
+		}
+		return code
+	}
+	if ctx.JSON {
+		res := ctx.res
+		if res == nil {
+			res = map[string]any{}
+		}
+		writeJSON(stdout, res)
+	}
+	return ExitOK
+}
+
+// exitCode returns the exit code for the error returned by a command.
+func exitCode(err error) int {
+	var exitErr *exitError
+	switch {
+	case errors.As(err, &exitErr):
+		return exitErr.code
+	case errors.Is(err, pack.ErrWrongKey):
+		return ExitWrongKey
+	case errors.Is(err, pack.ErrKeyRequired):
+		return ExitUsage
+	}
+	return ExitError
+}
+
+func writeJSON(w io.Writer, v any) {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(v)
+}
+
+// newFlagSet returns a flag set with the flags of the command registered, and the function that runs the
+// command with the values of those flags.
+func newFlagSet(cmd *Command) (*flag.FlagSet, RunFunc) {
+	fs := flag.NewFlagSet(cmd.Name, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	return fs, cmd.Setup(fs)
+}
+
+// parseFlags parses the flags of fs from args and returns the remaining positional arguments. Unlike
+// flag.FlagSet.Parse, flags may also follow positional arguments.
+func parseFlags(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		if fs.NArg() == 0 {
+			return positional, nil
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
+
+func findCommand(name string) (*Command, bool) {
+	for _, cmd := range commands {
+		if cmd.Name == name {
+			return cmd, true
+		}
+	}
+	return nil, false
+}
+
+func printHelp(w io.Writer) {
+	_, _ = fmt.Fprintln(w, "Usage:")
+	_, _ = fmt.Fprintln(w, "   bedrockpack <command> [flags] [arguments]")
+	_, _ = fmt.Fprintln(w)
+	_, _ = fmt.Fprintln(w, "Commands:")
+	for _, cmd := range commands {
+		_, _ = fmt.Fprintf(w, "   %-10s %s\n", cmd.Name, cmd.Short)
+	}
+	_, _ = fmt.Fprintln(w)
+	_, _ = fmt.Fprintln(w, "Every command accepts --json for machine-readable output.")
+	_, _ = fmt.Fprintln(w, "Run 'bedrockpack help <command>' for more information about a command.")
+}
+
+func printCommandHelp(w io.Writer, cmd *Command) {
+	_, _ = fmt.Fprintln(w, "Usage:")
+	_, _ = fmt.Fprintf(w, "   bedrockpack %s\n\n", cmd.Usage)
+	_, _ = fmt.Fprintln(w, "   "+cmd.Short)
+	if cmd.Long != "" {
+		for _, line := range strings.Split(cmd.Long, "\n") {
+			_, _ = fmt.Fprintln(w, "   "+line)
+		}
+	}
+
+	fs, _ := newFlagSet(cmd)
+	var names []string
+	fs.VisitAll(func(f *flag.Flag) {
+		names = append(names, f.Name)
+	})
+	if len(names) == 0 {
+		return
+	}
+	sort.Strings(names)
+	_, _ = fmt.Fprintln(w)
+	_, _ = fmt.Fprintln(w, "Flags:")
+	for _, name := range names {
+		argName, usage := flag.UnquoteUsage(fs.Lookup(name))
+		_, _ = fmt.Fprintf(w, "   --%s %s\n        %s\n", name, argName, usage)
+	}
+}
+
+// stringsFlag is a flag.Value that may be set multiple times, collecting every value.
+type stringsFlag []string
+
+// String ...
+func (s *stringsFlag) String() string {
+	return strings.Join(*s, ",")
+}
+
+// Set ...
+func (s *stringsFlag) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
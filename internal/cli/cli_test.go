@@ -0,0 +1,207 @@
+package cli
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testKey = "0123Z5678K0123u567890123Z56789P1"
+
+// writeTestPack writes a resource pack archive with a few files to the path given.
+func writeTestPack(t *testing.T, path string) {
+	t.Helper()
+	files := map[string]string{
+		"manifest.json":           `{"format_version":2,"header":{"name":"test","description":"","uuid":"6f0b5b1e-4c5c-4a4e-9d59-2f8a3b2f6e11","version":[1,0,0],"min_engine_version":[1,20,0]},"modules":[{"type":"resources","uuid":"8a3a7a0c-3d1b-4b8f-8a8e-6c1f4e0e2b22","version":[1,0,0]}]}`,
+		"models/entity/test.json": `{"format_version":"1.12.0"}`,
+		"texts/en_US.lang":        "pack.name=Test",
+	}
+	var buf bytes.Buffer
+	arc := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := arc.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := arc.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0666); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// runJSON runs the command line given with --json and returns the exit code and the JSON output.
+func runJSON(t *testing.T, args ...string) (int, map[string]any) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	code := run(append(args, "--json"), &stdout, &stderr)
+	var res map[string]any
+	if err := json.Unmarshal(stdout.Bytes(), &res); err != nil {
+		t.Fatalf("%v: invalid JSON output %q (stderr %q): %v", args, stdout.String(), stderr.String(), err)
+	}
+	return code, res
+}
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	packPath := filepath.Join(dir, "pack.zip")
+	writeTestPack(t, packPath)
+
+	tests := []struct {
+		name  string
+		args  []string
+		code  int
+		check func(t *testing.T, res map[string]any)
+	}{
+		{name: "encrypt", args: []string{"encrypt", packPath, testKey, "--exclude", "texts/*"}, code: ExitOK, check: func(t *testing.T, res map[string]any) {
+			if res["key"] != testKey || res["path"] != packPath || res["uuid"] == "" {
+				t.Errorf("unexpected result %v", res)
+			}
+		}},
+		{name: "verify", args: []string{"verify", packPath, testKey}, code: ExitOK, check: func(t *testing.T, res map[string]any) {
+			if res["valid"] != true {
+				t.Errorf("unexpected result %v", res)
+			}
+		}},
+		{name: "verify wrong key", args: []string{"verify", packPath, strings.Repeat("a", 32)}, code: ExitCheckFailed, check: func(t *testing.T, res map[string]any) {
+			if result, _ := res["result"].(map[string]any); result["valid"] != false || res["exit_code"] != float64(ExitCheckFailed) {
+				t.Errorf("unexpected result %v", res)
+			}
+		}},
+		{name: "cat excluded file", args: []string{"cat", packPath, "texts/en_US.lang", "--key", testKey}, code: ExitOK, check: func(t *testing.T, res map[string]any) {
+			if res["encrypted"] != false {
+				t.Errorf("excluded file is encrypted: %v", res)
+			}
+		}},
+		{name: "decrypt without key", args: []string{"decrypt", packPath}, code: ExitUsage},
+		{name: "decrypt wrong key", args: []string{"decrypt", packPath, strings.Repeat("a", 32)}, code: ExitWrongKey, check: func(t *testing.T, res map[string]any) {
+			if res["exit_code"] != float64(ExitWrongKey) || res["error"] == "" {
+				t.Errorf("unexpected result %v", res)
+			}
+		}},
+		{name: "decrypt", args: []string{"decrypt", packPath, testKey, "--no-backup"}, code: ExitOK, check: func(t *testing.T, res map[string]any) {
+			if res["path"] != packPath {
+				t.Errorf("unexpected result %v", res)
+			}
+		}},
+		// Flags of an earlier run must not carry over: without --exclude, every file is encrypted.
+		{name: "encrypt again", args: []string{"encrypt", packPath, testKey, "--no-backup"}, code: ExitOK},
+		{name: "cat file", args: []string{"cat", packPath, "texts/en_US.lang", "--key", testKey}, code: ExitOK, check: func(t *testing.T, res map[string]any) {
+			if res["encrypted"] != true {
+				t.Errorf("file is not encrypted: %v", res)
+			}
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if test.code == ExitUsage {
+				if code := run(test.args, &bytes.Buffer{}, &bytes.Buffer{}); code != test.code {
+					t.Fatalf("exit code %d, want %d", code, test.code)
+				}
+				return
+			}
+			code, res := runJSON(t, test.args...)
+			if code != test.code {
+				t.Fatalf("exit code %d, want %d: %v", code, test.code, res)
+			}
+			if test.check != nil {
+				test.check(t, res)
+			}
+		})
+	}
+}
+
+func TestRunUsage(t *testing.T) {
+	for _, args := range [][]string{nil, {"unknown"}, {"encrypt"}, {"encrypt", "--unknown-flag", "pack.zip"}} {
+		var stdout, stderr bytes.Buffer
+		if code := run(args, &stdout, &stderr); code != ExitUsage {
+			t.Errorf("%v: exit code %d, want %d", args, code, ExitUsage)
+		}
+	}
+	var stdout bytes.Buffer
+	if code := run([]string{"help", "encrypt"}, &stdout, &bytes.Buffer{}); code != ExitOK || !strings.Contains(stdout.String(), "--seed") {
+		t.Errorf("help of encrypt: exit code %d, output %q", code, stdout.String())
+	}
+}
+
+func TestDirectoryPackRead(t *testing.T) {
+	dir := t.TempDir()
+	packPath, packDir := filepath.Join(dir, "pack.zip"), filepath.Join(dir, "pack")
+	writeTestPack(t, packPath)
+	// Extracting without a key leaves the files encrypted, which makes an encrypted directory pack.
+	for _, args := range [][]string{{"encrypt", packPath, testKey}, {"extract", packPath, packDir}} {
+		if code, res := runJSON(t, args...); code != ExitOK {
+			t.Fatalf("%v: exit code %d: %v", args, code, res)
+		}
+	}
+
+	code, res := runJSON(t, "ls", packDir, "--key", testKey)
+	if code != ExitOK {
+		t.Fatalf("ls: exit code %d: %v", code, res)
+	}
+	files, _ := res["files"].([]any)
+	found := false
+	for _, file := range files {
+		if file, _ := file.(map[string]any); file["path"] == "texts/en_US.lang" {
+			found = file["encrypted"] == true
+		}
+	}
+	if !found {
+		t.Fatalf("ls: encrypted texts/en_US.lang not listed: %v", res)
+	}
+
+	var stdout bytes.Buffer
+	if code := run([]string{"cat", packDir, "texts/en_US.lang", "--key", testKey}, &stdout, &bytes.Buffer{}); code != ExitOK || stdout.String() != "pack.name=Test" {
+		t.Fatalf("cat: exit code %d, output %q", code, stdout.String())
+	}
+}
+
+func TestDirectoryPackWrite(t *testing.T) {
+	dir := t.TempDir()
+	packPath, packDir := filepath.Join(dir, "pack.zip"), filepath.Join(dir, "pack")
+	writeTestPack(t, packPath)
+	for _, args := range [][]string{
+		{"extract", packPath, packDir},
+		{"encrypt", packDir, testKey},
+		{"rm", packDir, "texts/en_US.lang", "--key", testKey},
+	} {
+		if code, res := runJSON(t, args...); code != ExitOK {
+			t.Fatalf("%v: exit code %d: %v", args, code, res)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(packDir, "texts", "en_US.lang")); !os.IsNotExist(err) {
+		t.Fatalf("removed file still on disk: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(packDir+".bak", "texts", "en_US.lang")); err != nil {
+		t.Fatalf("removed file not in backup: %v", err)
+	}
+
+	if code, res := runJSON(t, "decrypt", packDir, testKey); code != ExitOK {
+		t.Fatalf("decrypt: exit code %d: %v", code, res)
+	}
+	if _, err := os.Stat(filepath.Join(packDir, "contents.json")); !os.IsNotExist(err) {
+		t.Fatalf("contents.json still on disk after decrypt: %v", err)
+	}
+	code, res := runJSON(t, "info", packDir)
+	if code != ExitOK || res["encrypted"] != false {
+		t.Fatalf("info after decrypt: exit code %d: %v", code, res)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		if strings.Contains(entry.Name(), ".tmp") {
+			t.Fatalf("temporary directory %s left behind", entry.Name())
+		}
+	}
+}
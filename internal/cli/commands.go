@@ -0,0 +1,163 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"github.com/akmalfairuz/bedrockpack/pack"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// commands holds every command of bedrockpack, in the order they are listed in the help.
+var commands = []*Command{
+	newCommand,
+	manifestCommand,
+	versionCommand,
+	uuidCommand,
+	encryptCommand,
+	decryptCommand,
+	rekeyCommand,
+	verifyCommand,
+	catCommand,
+	traceCommand,
+	infoCommand,
+	lsCommand,
+	extractCommand,
+	addCommand,
+	rmCommand,
+	stealCommand,
+}
+
+// outputFlags are the flags of commands that write a modified pack.
+type outputFlags struct {
+	output   string
+	noBackup bool
+}
+
+func (o *outputFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&o.output, "output", "", "`path` to write the resource pack to instead of overwriting it")
+	fs.BoolVar(&o.noBackup, "no-backup", false, "do not write a .bak copy before overwriting the resource pack")
+}
+
+// path returns the path to write the pack loaded from the path given to.
+func (o *outputFlags) path(input string) string {
+	if o.output != "" {
+		return o.output
+	}
+	return input
+}
+
+// save writes the pack loaded from the path given. If the pack is written over the original file or directory,
+// a backup of the original is made first unless disabled.
+func (o *outputFlags) save(ctx *Context, rp *pack.ResourcePack, input string) (string, error) {
+	output := o.path(input)
+	if stat, err := os.Stat(output); err == nil && stat.IsDir() {
+		return output, saveDir(ctx, rp, output, stat.Mode().Perm(), output == input && !o.noBackup)
+	}
+	if output == input && !o.noBackup {
+		ctx.Println("Backup resource pack...")
+		original, err := os.ReadFile(input)
+		if err != nil {
+			return "", err
+		}
+		if err := os.WriteFile(input+".bak", original, 0777); err != nil {
+			return "", err
+		}
+	}
+	if err := rp.Save(output); err != nil {
+		return "", err
+	}
+	return output, nil
+}
+
+// saveDir writes the pack to the directory at the path given, with the permissions passed. The pack is written
+// to a new directory next to it, which then replaces the directory, so that files no longer in the pack do not
+// remain. The directory replaced is kept as a .bak directory if backup is true.
+func saveDir(ctx *Context, rp *pack.ResourcePack, dir string, perm os.FileMode, backup bool) error {
+	dir = filepath.Clean(dir)
+	tmp, err := os.MkdirTemp(filepath.Dir(dir), filepath.Base(dir)+".tmp")
+	if err != nil {
+		return err
+	}
+	if err := rp.SaveToDir(tmp); err != nil {
+		_ = os.RemoveAll(tmp)
+		return err
+	}
+	if err := os.Chmod(tmp, perm); err != nil {
+		_ = os.RemoveAll(tmp)
+		return err
+	}
+
+	old := tmp + ".old"
+	if backup {
+		ctx.Println("Backup resource pack...")
+		old = dir + ".bak"
+		if err := os.RemoveAll(old); err != nil {
+			_ = os.RemoveAll(tmp)
+			return err
+		}
+	}
+	if err := os.Rename(dir, old); err != nil {
+		_ = os.RemoveAll(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, dir); err != nil {
+		_ = os.Rename(old, dir)
+		_ = os.RemoveAll(tmp)
+		return err
+	}
+	if !backup {
+		return os.RemoveAll(old)
+	}
+	return nil
+}
+
+// keyFlags are the flags of commands that take a key.
+type keyFlags struct {
+	key     string
+	keyFile string
+}
+
+func (k *keyFlags) register(fs *flag.FlagSet, withKey bool) {
+	if withKey {
+		fs.StringVar(&k.key, "key", "", "`key` of the resource pack")
+	}
+	fs.StringVar(&k.keyFile, "key-file", "", "`file` to read the key of the resource pack from")
+}
+
+// resolve returns the key from --key-file, --key or the positional argument at index i, in that order. It
+// returns nil if no key was given.
+func (k *keyFlags) resolve(args []string, i int) ([]byte, error) {
+	switch {
+	case k.keyFile != "":
+		b, err := os.ReadFile(k.keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("read key file: %w", err)
+		}
+		return []byte(strings.TrimSpace(string(b))), nil
+	case k.key != "":
+		return []byte(k.key), nil
+	case len(args) > i:
+		return []byte(args[i]), nil
+	}
+	return nil, nil
+}
+
+// require returns the key like resolve, but returns a usage error if no key was given.
+func (k *keyFlags) require(args []string, i int) ([]byte, error) {
+	key, err := k.resolve(args, i)
+	if err == nil && key == nil {
+		err = withExitCode(ExitUsage, fmt.Errorf("no key given"))
+	}
+	return key, err
+}
+
+func loadPack(ctx *Context, path string) (*pack.ResourcePack, error) {
+	ctx.Println("Loading " + path + " resource pack...")
+	rp, err := pack.LoadResourcePack(path)
+	if err != nil {
+		return nil, fmt.Errorf("load %s: %w", path, err)
+	}
+	return rp, nil
+}
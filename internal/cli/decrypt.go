@@ -0,0 +1,55 @@
+package cli
+
+import (
+	"errors"
+	"flag"
+	"github.com/akmalfairuz/bedrockpack/pack"
+)
+
+type decryptFlags struct {
+	outputFlags
+	keyFlags
+}
+
+var decryptCommand = &Command{
+	Name:    "decrypt",
+	Usage:   "decrypt [flags] <path to resource pack> <key>",
+	Short:   "Decrypt the resource pack using the given key",
+	MinArgs: 1,
+	Setup: func(fs *flag.FlagSet) RunFunc {
+		f := &decryptFlags{}
+		f.outputFlags.register(fs)
+		f.keyFlags.register(fs, false)
+		return f.run
+	},
+}
+
+func (f *decryptFlags) run(ctx *Context, args []string) error {
+	rp, err := loadPack(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	key, err := f.keyFlags.require(args, 1)
+	if err != nil {
+		return err
+	}
+
+	var warnings []string
+	ctx.Println("Decrypting resource pack with key " + string(key) + "...")
+	if err := rp.Decrypt(key); err != nil {
+		var decryptErr *pack.DecryptError
+		if !errors.As(err, &decryptErr) {
+			return err
+		}
+		ctx.Println("Warning: " + err.Error())
+		warnings = append(warnings, err.Error())
+	}
+
+	output, err := f.outputFlags.save(ctx, rp, args[0])
+	if err != nil {
+		return err
+	}
+	ctx.Println("Resource pack decrypted!")
+	ctx.Result(map[string]any{"path": output, "uuid": rp.UUID(), "warnings": warnings})
+	return nil
+}
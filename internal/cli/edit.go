@@ -0,0 +1,96 @@
+package cli
+
+import (
+	"flag"
+	"os"
+)
+
+type addFlags struct {
+	outputFlags
+	keyFlags
+}
+
+var addCommand = &Command{
+	Name:    "add",
+	Usage:   "add [flags] <path to resource pack> <path in resource pack> <file>",
+	Short:   "Add a file to the resource pack, replacing any existing file",
+	Long:    "Given the key of an encrypted resource pack, the file is encrypted and listed in contents.json.",
+	MinArgs: 3,
+	Setup: func(fs *flag.FlagSet) RunFunc {
+		f := &addFlags{}
+		f.outputFlags.register(fs)
+		f.keyFlags.register(fs, true)
+		return f.run
+	},
+}
+
+func (f *addFlags) run(ctx *Context, args []string) error {
+	key, err := f.keyFlags.resolve(nil, 0)
+	if err != nil {
+		return err
+	}
+	rp, err := loadPack(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	content, err := os.ReadFile(args[2])
+	if err != nil {
+		return err
+	}
+
+	ctx.Printf("Adding %s to resource pack...\n", args[1])
+	if err := rp.AddFile(args[1], content, key); err != nil {
+		return err
+	}
+	output, err := f.outputFlags.save(ctx, rp, args[0])
+	if err != nil {
+		return err
+	}
+	ctx.Println("File added!")
+	ctx.Result(map[string]any{"path": output, "added": args[1]})
+	return nil
+}
+
+type rmFlags struct {
+	outputFlags
+	keyFlags
+}
+
+var rmCommand = &Command{
+	Name:    "rm",
+	Usage:   "rm [flags] <path to resource pack> <path in resource pack>...",
+	Short:   "Remove files from the resource pack",
+	Long:    "Given the key of an encrypted resource pack, the files are also removed from contents.json.",
+	MinArgs: 2,
+	Setup: func(fs *flag.FlagSet) RunFunc {
+		f := &rmFlags{}
+		f.outputFlags.register(fs)
+		f.keyFlags.register(fs, true)
+		return f.run
+	},
+}
+
+func (f *rmFlags) run(ctx *Context, args []string) error {
+	key, err := f.keyFlags.resolve(nil, 0)
+	if err != nil {
+		return err
+	}
+	rp, err := loadPack(ctx, args[0])
+	if err != nil {
+		return err
+	}
+
+	for _, name := range args[1:] {
+		ctx.Printf("Removing %s from resource pack...\n", name)
+		if err := rp.RemoveFile(name, key); err != nil {
+			return err
+		}
+	}
+	output, err := f.outputFlags.save(ctx, rp, args[0])
+	if err != nil {
+		return err
+	}
+	ctx.Println("Files removed!")
+	ctx.Result(map[string]any{"path": output, "removed": args[1:]})
+	return nil
+}
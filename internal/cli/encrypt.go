@@ -0,0 +1,163 @@
+package cli
+
+import (
+	"crypto/sha256"
+	"flag"
+	"github.com/akmalfairuz/bedrockpack/pack"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type encryptFlags struct {
+	outputFlags
+	keyFlags
+	opts       pack.EncryptOptions
+	recipients string
+	seed       string
+	noMinify   bool
+	noCompress bool
+	keepUUID   bool
+}
+
+var encryptCommand = &Command{
+	Name:  "encrypt",
+	Usage: "encrypt [flags] <path to resource pack> [key]",
+	Short: "Encrypt the resource pack using either the given key or a generated key",
+	Long: `JSON files are minified, .png files are compressed and the UUID is regenerated unless disabled.
+The key is written next to the resource pack in a .key.txt file.`,
+	MinArgs: 1,
+	Setup: func(fs *flag.FlagSet) RunFunc {
+		f := &encryptFlags{}
+		f.outputFlags.register(fs)
+		f.keyFlags.register(fs, false)
+		fs.Var((*stringsFlag)(&f.opts.Include), "include", "`glob` pattern of files to encrypt (repeatable)")
+		fs.Var((*stringsFlag)(&f.opts.Exclude), "exclude", "`glob` pattern of files to leave unencrypted (repeatable)")
+		fs.StringVar(&f.recipients, "recipients", "", "`file` with one recipient per line to build a watermarked pack for")
+		fs.StringVar(&f.seed, "seed", "", "`seed` to derive the UUID and file keys from, for reproducible output")
+		fs.BoolVar(&f.noMinify, "no-minify", false, "do not minify JSON files")
+		fs.BoolVar(&f.noCompress, "no-compress", false, "do not compress .png files")
+		fs.BoolVar(&f.keepUUID, "keep-uuid", false, "do not regenerate the UUID of the resource pack")
+		return f.run
+	},
+}
+
+func (f *encryptFlags) run(ctx *Context, args []string) error {
+	rp, err := loadPack(ctx, args[0])
+	if err != nil {
+		return err
+	}
+
+	key, err := f.keyFlags.resolve(args, 1)
+	if err != nil {
+		return err
+	}
+	if key == nil {
+		key = pack.GenerateKey()
+	}
+
+	opts := f.opts
+	var uuidSeed []byte
+	if f.seed != "" {
+		opts.Seed = []byte(f.seed)
+		sum := sha256.Sum256([]byte(f.seed))
+		uuidSeed = sum[:]
+	}
+
+	if !f.keepUUID {
+		ctx.Println("Regenerate resource pack UUID...")
+		if err := rp.RegenerateUUID(uuidSeed); err != nil {
+			return err
+		}
+		ctx.Printf("New resource pack UUID: %s\n", rp.UUID())
+	}
+
+	if !f.noMinify {
+		ctx.Println("Minifying JSON files in resource pack...")
+		if err := rp.MinifyJSONFiles(); err != nil {
+			return err
+		}
+	}
+
+	if !f.noCompress {
+		ctx.Println("Compressing .png files in resource pack...")
+		if err := rp.CompressPNGFiles(); err != nil {
+			return err
+		}
+	}
+
+	if f.recipients != "" {
+		return f.encryptForRecipients(ctx, rp, args[0], opts)
+	}
+
+	ctx.Println("Encrypting resource pack with key " + string(key) + "...")
+	if err := rp.EncryptWithOptions(key, opts); err != nil {
+		return err
+	}
+
+	output, err := f.outputFlags.save(ctx, rp, args[0])
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(output+".key.txt", key, 0777); err != nil {
+		return err
+	}
+	ctx.Println("Resource pack encrypted!")
+	ctx.Result(map[string]any{"path": output, "uuid": rp.UUID(), "key": string(key)})
+	return nil
+}
+
+func (f *encryptFlags) encryptForRecipients(ctx *Context, rp *pack.ResourcePack, input string, opts pack.EncryptOptions) error {
+	recipients, err := readRecipients(f.recipients)
+	if err != nil {
+		return err
+	}
+
+	ctx.Printf("Building resource pack for %d recipients...\n", len(recipients))
+	builds, err := rp.BuildForRecipients(recipients, opts)
+	if err != nil {
+		return err
+	}
+	results := make([]map[string]any, 0, len(builds))
+	for _, build := range builds {
+		buildPath := recipientPath(f.outputFlags.path(input), build.Recipient)
+		if err := build.Pack.Save(buildPath); err != nil {
+			return err
+		}
+		if err := os.WriteFile(buildPath+".key.txt", build.Key, 0777); err != nil {
+			return err
+		}
+		ctx.Printf("Resource pack for %s saved in %s with key %s (UUID %s)\n", build.Recipient, buildPath, build.Key, build.Pack.UUID())
+		results = append(results, map[string]any{"recipient": build.Recipient, "path": buildPath, "uuid": build.Pack.UUID(), "key": string(build.Key)})
+	}
+	ctx.Println("Resource packs encrypted!")
+	ctx.Result(map[string]any{"builds": results})
+	return nil
+}
+
+// readRecipients reads one recipient per line from the file at the path given, skipping empty lines and
+// lines starting with #.
+func readRecipients(path string) ([]string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var recipients []string
+	for _, line := range strings.Split(string(b), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		recipients = append(recipients, line)
+	}
+	return recipients, nil
+}
+
+// recipientPath returns the path to save the build of the pack at the path given for a recipient.
+func recipientPath(packPath, recipient string) string {
+	for _, char := range []string{"\\", "/", ":", "*", "?", "\"", "<", ">", "|", " "} {
+		recipient = strings.ReplaceAll(recipient, char, "_")
+	}
+	ext := filepath.Ext(packPath)
+	return strings.TrimSuffix(packPath, ext) + "_" + recipient + ext
+}
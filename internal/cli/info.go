@@ -0,0 +1,126 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"github.com/akmalfairuz/bedrockpack/pack"
+	"path/filepath"
+	"strings"
+)
+
+type infoFlags struct {
+	top int
+}
+
+var infoCommand = &Command{
+	Name:    "info",
+	Usage:   "info [flags] <path to pack, directory or .mcaddon>",
+	Short:   "Print information about a pack without modifying it",
+	MinArgs: 1,
+	Setup: func(fs *flag.FlagSet) RunFunc {
+		f := &infoFlags{}
+		fs.IntVar(&f.top, "top", 10, "number of biggest files to list")
+		return f.run
+	},
+}
+
+func (f *infoFlags) run(ctx *Context, args []string) error {
+	var packs []*pack.ResourcePack
+	if strings.EqualFold(filepath.Ext(args[0]), ".mcaddon") {
+		addon, err := pack.LoadAddon(args[0])
+		if err != nil {
+			return err
+		}
+		packs = addon
+	} else {
+		rp, err := pack.LoadResourcePack(args[0])
+		if err != nil {
+			return err
+		}
+		packs = append(packs, rp)
+	}
+
+	infos := make([]pack.Info, 0, len(packs))
+	for i, rp := range packs {
+		info, err := rp.Info(f.top)
+		if err != nil {
+			return err
+		}
+		infos = append(infos, info)
+		if i > 0 {
+			ctx.Println()
+		}
+		printInfo(ctx, info)
+	}
+	if len(infos) == 1 {
+		ctx.Result(infos[0])
+	} else {
+		ctx.Result(map[string]any{"packs": infos})
+	}
+	return nil
+}
+
+func printInfo(ctx *Context, info pack.Info) {
+	m := info.Manifest
+	ctx.Printf("Name:               %s\n", m.Header.Name)
+	ctx.Printf("Description:        %s\n", m.Header.Description)
+	ctx.Printf("UUID:               %s\n", m.Header.UUID)
+	ctx.Printf("Version:            %s\n", m.Header.Version)
+	ctx.Printf("Min engine version: %s\n", m.Header.MinEngineVersion)
+	ctx.Printf("Format version:     %d\n", m.FormatVersion)
+
+	ctx.Printf("Modules:            %d\n", len(m.Modules))
+	for _, module := range m.Modules {
+		ctx.Printf("   %-15s %s %s\n", module.Type, module.UUID, module.Version)
+	}
+	if len(m.Dependencies) > 0 {
+		ctx.Printf("Dependencies:       %d\n", len(m.Dependencies))
+		for _, dep := range m.Dependencies {
+			name := dep.UUID
+			if name == "" {
+				name = dep.ModuleName
+			}
+			ctx.Printf("   %s %s\n", name, dep.Version)
+		}
+	}
+	if len(m.Subpacks) > 0 {
+		ctx.Printf("Subpacks:           %d\n", len(m.Subpacks))
+		for _, subpack := range m.Subpacks {
+			ctx.Printf("   %-15s %s (memory tier %d)\n", subpack.FolderName, subpack.Name, subpack.MemoryTier)
+		}
+	}
+
+	ctx.Printf("Encrypted:          %t\n", info.Encrypted)
+	if h := info.ContentsHeader; h != nil {
+		ctx.Printf("contents.json:      version %d, UUID %s\n", h.Version, h.UUID)
+		if h.Error != "" {
+			ctx.Printf("   invalid header: %s\n", h.Error)
+		}
+	}
+
+	ctx.Printf("Files:              %d (%s)\n", info.FileCount, formatSize(info.TotalSize))
+	for _, category := range info.Categories {
+		ctx.Printf("   %-15s %6d files %12s\n", category.Name, category.Count, formatSize(category.Size))
+	}
+	if len(info.BiggestFiles) > 0 {
+		ctx.Println("Biggest files:")
+		for _, file := range info.BiggestFiles {
+			ctx.Printf("   %12s  %s\n", formatSize(file.Size), file.Path)
+		}
+	}
+	ctx.Printf("Hash:               %s\n", info.Hash)
+}
+
+// formatSize formats the number of bytes given in a human-readable form.
+func formatSize(size int) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := unit, 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGT"[exp])
+}
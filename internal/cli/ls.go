@@ -0,0 +1,144 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"github.com/akmalfairuz/bedrockpack/pack"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type lsFlags struct {
+	keyFlags
+}
+
+var lsCommand = &Command{
+	Name:    "ls",
+	Usage:   "ls [flags] <path to resource pack> [glob]",
+	Short:   "List the files of the resource pack with their sizes",
+	Long:    "Given the key of an encrypted resource pack, encrypted files are marked with an E.",
+	MinArgs: 1,
+	Setup: func(fs *flag.FlagSet) RunFunc {
+		f := &lsFlags{}
+		f.keyFlags.register(fs, true)
+		return f.run
+	},
+}
+
+func (f *lsFlags) run(ctx *Context, args []string) error {
+	key, err := f.keyFlags.resolve(nil, 0)
+	if err != nil {
+		return err
+	}
+	p, err := pack.OpenEncryptedPack(args[0], key)
+	if err != nil {
+		return err
+	}
+	defer p.Close()
+
+	type entry struct {
+		Path      string `json:"path"`
+		Size      int64  `json:"size"`
+		Encrypted bool   `json:"encrypted"`
+	}
+	entries := make([]entry, 0)
+	for _, name := range p.FileNames() {
+		if len(args) > 1 && !pack.MatchGlob(args[1], name) {
+			continue
+		}
+		stat, err := fs.Stat(p, name)
+		if err != nil {
+			return err
+		}
+		e := entry{Path: name, Size: stat.Size(), Encrypted: p.Encrypted(name)}
+		entries = append(entries, e)
+
+		flag := " "
+		if e.Encrypted {
+			flag = "E"
+		}
+		ctx.Printf("%12s %s %s\n", formatSize(int(e.Size)), flag, e.Path)
+	}
+	ctx.Result(map[string]any{"files": entries})
+	return nil
+}
+
+type extractFlags struct {
+	keyFlags
+}
+
+var extractCommand = &Command{
+	Name:  "extract",
+	Usage: "extract [flags] <path to resource pack> <destination directory>",
+	Short: "Extract the files of the resource pack to a directory",
+	Long: `Given the key of an encrypted resource pack, files are decrypted while they are extracted and
+contents.json is left out. Without a key, files are extracted as they are stored.`,
+	MinArgs: 2,
+	Setup: func(fs *flag.FlagSet) RunFunc {
+		f := &extractFlags{}
+		f.keyFlags.register(fs, true)
+		return f.run
+	},
+}
+
+func (f *extractFlags) run(ctx *Context, args []string) error {
+	key, err := f.keyFlags.resolve(nil, 0)
+	if err != nil {
+		return err
+	}
+	p, err := pack.OpenEncryptedPack(args[0], key)
+	if err != nil {
+		return err
+	}
+	defer p.Close()
+
+	count := 0
+	skipped := make([]map[string]string, 0)
+	for _, name := range p.FileNames() {
+		if len(key) > 0 && (name == "contents.json" || strings.HasPrefix(name, "subpacks/") && strings.HasSuffix(name, "/contents.json")) {
+			continue
+		}
+		// A file that cannot be extracted is reported and skipped, so that the other files are still extracted.
+		if err := extractFile(p, name, args[1]); err != nil {
+			ctx.Printf("Skipped %s: %s\n", name, err)
+			skipped = append(skipped, map[string]string{"path": name, "error": err.Error()})
+			continue
+		}
+		count++
+	}
+	ctx.Printf("Extracted %d files to %s\n", count, args[1])
+	ctx.Result(map[string]any{"path": args[1], "files": count, "skipped": skipped})
+	if len(skipped) > 0 {
+		return fmt.Errorf("%d files could not be extracted", len(skipped))
+	}
+	return nil
+}
+
+// extractFile copies the file with the name given from the pack into the destination directory. Names that are
+// not valid paths within the pack, such as ones leaving the directory, are rejected before anything is written.
+func extractFile(p *pack.EncryptedPack, name, dir string) error {
+	if !fs.ValidPath(name) {
+		return fmt.Errorf("invalid file name %q", name)
+	}
+	dest := filepath.Join(dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(dest), 0777); err != nil {
+		return err
+	}
+	src, err := p.Open(name)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		_ = dst.Close()
+		return err
+	}
+	return dst.Close()
+}
@@ -0,0 +1,183 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"github.com/akmalfairuz/bedrockpack/pack"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type manifestFlags struct {
+	outputFlags
+}
+
+var manifestCommand = &Command{
+	Name:  "manifest",
+	Usage: "manifest set [flags] <path to pack> <field=value>... [other packs of the add-on...]",
+	Short: "Set fields of the manifest of a pack",
+	Long: `Fields: ` + strings.Join(pack.ManifestFields, ", ") + `.
+Setting the version also sets the version of every module, and the dependency version of other packs of the
+add-on that depend on the pack. If the pack is a directory, the other packs of the add-on are the pack
+directories next to it.`,
+	MinArgs: 3,
+	Setup: func(fs *flag.FlagSet) RunFunc {
+		f := &manifestFlags{}
+		f.outputFlags.register(fs)
+		return f.run
+	},
+}
+
+func (f *manifestFlags) run(ctx *Context, args []string) error {
+	if args[0] != "set" {
+		return withExitCode(ExitUsage, fmt.Errorf("unknown manifest subcommand %q", args[0]))
+	}
+	rp, err := loadPack(ctx, args[1])
+	if err != nil {
+		return err
+	}
+
+	var others []string
+	set := map[string]string{}
+	for i, arg := range args[2:] {
+		key, value, ok := strings.Cut(arg, "=")
+		if !ok {
+			// Every argument after the fields is another pack of the add-on.
+			others = args[2+i:]
+			break
+		}
+		if err := rp.SetManifestField(key, value); err != nil {
+			return withExitCode(ExitUsage, err)
+		}
+		set[key] = value
+		ctx.Printf("Set %s to %s\n", key, value)
+	}
+	if len(set) == 0 {
+		return withExitCode(ExitUsage, fmt.Errorf("no fields given"))
+	}
+
+	output, err := f.outputFlags.save(ctx, rp, args[1])
+	if err != nil {
+		return err
+	}
+	res := map[string]any{"path": output, "set": set}
+	if _, ok := set["version"]; ok {
+		updated, err := updateDependents(ctx, rp, args[1], others, f.noBackup)
+		res["dependents"] = updated
+		if err != nil {
+			ctx.Result(res)
+			return err
+		}
+	}
+	ctx.Println("Manifest updated!")
+	ctx.Result(res)
+	return nil
+}
+
+type versionFlags struct {
+	outputFlags
+}
+
+var versionCommand = &Command{
+	Name:  "version",
+	Usage: "version bump [flags] major|minor|patch <path to pack> [other packs of the add-on...]",
+	Short: "Bump the version of a pack and of its modules",
+	Long: `Other packs of the add-on that depend on the pack get their dependency version updated. If the pack
+is a directory and no other packs are given, they are the pack directories next to it.`,
+	MinArgs: 3,
+	Setup: func(fs *flag.FlagSet) RunFunc {
+		f := &versionFlags{}
+		f.outputFlags.register(fs)
+		return f.run
+	},
+}
+
+func (f *versionFlags) run(ctx *Context, args []string) error {
+	if args[0] != "bump" {
+		return withExitCode(ExitUsage, fmt.Errorf("unknown version subcommand %q", args[0]))
+	}
+	rp, err := loadPack(ctx, args[2])
+	if err != nil {
+		return err
+	}
+	manifest, err := rp.Manifest()
+	if err != nil {
+		return err
+	}
+	v, err := rp.BumpVersion(pack.VersionPart(args[1]))
+	if err != nil {
+		return withExitCode(ExitUsage, err)
+	}
+	ctx.Printf("Bumped version from %s to %s\n", manifest.Header.Version, v)
+
+	output, err := f.outputFlags.save(ctx, rp, args[2])
+	if err != nil {
+		return err
+	}
+	res := map[string]any{"path": output, "old_version": manifest.Header.Version, "version": v}
+	updated, err := updateDependents(ctx, rp, args[2], args[3:], f.noBackup)
+	res["dependents"] = updated
+	ctx.Result(res)
+	return err
+}
+
+// updateDependents sets the dependency version on the pack passed, loaded from the path given, in every other
+// pack of the add-on that depends on it. If no other packs are given, the pack directories next to the pack are
+// used. It returns the paths of the packs updated.
+func updateDependents(ctx *Context, rp *pack.ResourcePack, path string, others []string, noBackup bool) ([]string, error) {
+	manifest, err := rp.Manifest()
+	if err != nil {
+		return nil, err
+	}
+	if len(others) == 0 {
+		others = siblingPacks(path)
+	}
+
+	updated := []string{}
+	for _, other := range others {
+		dependent, err := loadPack(ctx, other)
+		if err != nil {
+			return updated, err
+		}
+		ok, err := dependent.SetDependencyVersion(manifest.Header.UUID, manifest.Header.Version)
+		if err != nil {
+			return updated, fmt.Errorf("%s: %w", other, err)
+		}
+		if !ok {
+			continue
+		}
+		o := outputFlags{noBackup: noBackup}
+		if _, err := o.save(ctx, dependent, other); err != nil {
+			return updated, err
+		}
+		ctx.Printf("Updated dependency version in %s\n", other)
+		updated = append(updated, other)
+	}
+	return updated, nil
+}
+
+// siblingPacks returns the pack directories next to the pack directory at the path given. It returns nil if
+// the path is not a directory.
+func siblingPacks(path string) []string {
+	if stat, err := os.Stat(path); err != nil || !stat.IsDir() {
+		return nil
+	}
+	path = filepath.Clean(path)
+	parent := filepath.Dir(path)
+	entries, err := os.ReadDir(parent)
+	if err != nil {
+		return nil
+	}
+	var packs []string
+	for _, entry := range entries {
+		sibling := filepath.Join(parent, entry.Name())
+		if !entry.IsDir() || sibling == path {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(sibling, "manifest.json")); err == nil {
+			packs = append(packs, sibling)
+		}
+	}
+	return packs
+}
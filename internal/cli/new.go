@@ -0,0 +1,85 @@
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/akmalfairuz/bedrockpack/pack"
+	"os"
+	"path/filepath"
+)
+
+type newFlags struct {
+	name             string
+	description      string
+	minEngineVersion string
+}
+
+var newCommand = &Command{
+	Name:  "new",
+	Usage: "new [flags] resource|behavior|addon <directory>",
+	Short: "Create a new resource pack, behavior pack or add-on",
+	Long: `An add-on is created as a resource_pack and a behavior_pack directory, which depend on each other.
+The directory must not exist or be empty.`,
+	MinArgs: 2,
+	Setup: func(fs *flag.FlagSet) RunFunc {
+		f := &newFlags{}
+		fs.StringVar(&f.name, "name", "", "`name` of the pack, defaults to the name of the directory")
+		fs.StringVar(&f.description, "description", "", "`description` of the pack")
+		fs.StringVar(&f.minEngineVersion, "min-engine-version", "1.20.0", "minimum `version` of the game")
+		return f.run
+	},
+}
+
+func (f *newFlags) run(ctx *Context, args []string) error {
+	dir := args[1]
+	if entries, err := os.ReadDir(dir); err == nil && len(entries) > 0 {
+		return fmt.Errorf("directory %s is not empty", dir)
+	}
+	minEngineVersion, err := pack.ParseVersion(f.minEngineVersion)
+	if err != nil {
+		return withExitCode(ExitUsage, err)
+	}
+	conf := pack.NewConfig{
+		Name:             f.name,
+		Description:      f.description,
+		MinEngineVersion: minEngineVersion,
+	}
+	if conf.Name == "" {
+		conf.Name = filepath.Base(dir)
+	}
+
+	created := map[string]string{}
+	switch args[0] {
+	case "resource", "behavior":
+		conf.Type = pack.PackType(args[0])
+		p, err := pack.New(conf)
+		if err != nil {
+			return err
+		}
+		if err := p.SaveToDir(dir); err != nil {
+			return err
+		}
+		created[args[0]] = p.UUID()
+		ctx.Printf("Created %s pack %s in %s (UUID %s)\n", args[0], conf.Name, dir, p.UUID())
+	case "addon":
+		rp, bp, err := pack.NewAddon(conf)
+		if err != nil {
+			return err
+		}
+		if err := rp.SaveToDir(filepath.Join(dir, "resource_pack")); err != nil {
+			return err
+		}
+		if err := bp.SaveToDir(filepath.Join(dir, "behavior_pack")); err != nil {
+			return err
+		}
+		created["resource"], created["behavior"] = rp.UUID(), bp.UUID()
+		ctx.Printf("Created add-on %s in %s\n", conf.Name, dir)
+		ctx.Printf("   resource pack UUID: %s\n", rp.UUID())
+		ctx.Printf("   behavior pack UUID: %s\n", bp.UUID())
+	default:
+		return withExitCode(ExitUsage, errors.New("pack type must be resource, behavior or addon"))
+	}
+	ctx.Result(map[string]any{"path": dir, "uuids": created})
+	return nil
+}
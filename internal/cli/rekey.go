@@ -0,0 +1,65 @@
+package cli
+
+import (
+	"flag"
+	"github.com/akmalfairuz/bedrockpack/pack"
+	"os"
+)
+
+type rekeyFlags struct {
+	outputFlags
+	keyFlags
+	rotateFileKeys bool
+}
+
+var rekeyCommand = &Command{
+	Name:    "rekey",
+	Usage:   "rekey [flags] <path to resource pack> <old key> [new key]",
+	Short:   "Re-encrypt an encrypted resource pack with either the given key or a generated key",
+	Long:    "The resource pack is never decrypted on disk, and its files and UUID are left untouched.",
+	MinArgs: 1,
+	Setup: func(fs *flag.FlagSet) RunFunc {
+		f := &rekeyFlags{}
+		f.outputFlags.register(fs)
+		f.keyFlags.register(fs, false)
+		fs.BoolVar(&f.rotateFileKeys, "rotate-file-keys", false, "also generate new keys for every encrypted file")
+		return f.run
+	},
+}
+
+func (f *rekeyFlags) run(ctx *Context, args []string) error {
+	rp, err := loadPack(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	oldKey, err := f.keyFlags.require(args, 1)
+	if err != nil {
+		return err
+	}
+
+	// If the old key was read from a key file, the new key is the second positional argument.
+	newKeyArg := 2
+	if f.keyFile != "" {
+		newKeyArg = 1
+	}
+	newKey := pack.GenerateKey()
+	if len(args) > newKeyArg {
+		newKey = []byte(args[newKeyArg])
+	}
+
+	ctx.Println("Re-encrypting resource pack with key " + string(newKey) + "...")
+	if err := rp.Rekey(oldKey, newKey, f.rotateFileKeys); err != nil {
+		return err
+	}
+
+	output, err := f.outputFlags.save(ctx, rp, args[0])
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(output+".key.txt", newKey, 0777); err != nil {
+		return err
+	}
+	ctx.Println("Resource pack re-keyed!")
+	ctx.Result(map[string]any{"path": output, "uuid": rp.UUID(), "key": string(newKey)})
+	return nil
+}
@@ -0,0 +1,24 @@
+package cli
+
+import (
+	"flag"
+	"github.com/akmalfairuz/bedrockpack/internal/stealer"
+)
+
+var stealCommand = &Command{
+	Name:    "steal",
+	Usage:   "steal <server ip:port>",
+	Short:   "Steal the resource pack from a server and decrypt it automatically",
+	Long:    "Xbox authentication is required.",
+	MinArgs: 1,
+	Setup: func(fs *flag.FlagSet) RunFunc {
+		return runSteal
+	},
+}
+
+func runSteal(ctx *Context, args []string) error {
+	// Progress is written as it happens, to the error output with --json so that the result stays valid JSON.
+	saved, err := stealer.Run(args[0], ctx.Progress())
+	ctx.Result(map[string]any{"packs": saved})
+	return err
+}
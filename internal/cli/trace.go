@@ -0,0 +1,55 @@
+package cli
+
+import (
+	"errors"
+	"flag"
+	"github.com/akmalfairuz/bedrockpack/pack"
+)
+
+type traceFlags struct {
+	keyFlags
+	recipients string
+}
+
+var traceCommand = &Command{
+	Name:  "trace",
+	Usage: "trace --recipients <file> [flags] <path to leaked resource pack>",
+	Short: "Find which recipient a watermarked resource pack was built for",
+	Long: `The watermark is read from contents.json. If it was stripped, pass the key to check the watermark
+in the JSON files instead, which survives decryption. Exits with code 4 if no watermark was found.`,
+	MinArgs: 1,
+	Setup: func(fs *flag.FlagSet) RunFunc {
+		f := &traceFlags{}
+		f.keyFlags.register(fs, true)
+		fs.StringVar(&f.recipients, "recipients", "", "`file` with one recipient per line that builds were made for")
+		return f.run
+	},
+}
+
+func (f *traceFlags) run(ctx *Context, args []string) error {
+	if f.recipients == "" {
+		return withExitCode(ExitUsage, errors.New("--recipients is required"))
+	}
+	recipients, err := readRecipients(f.recipients)
+	if err != nil {
+		return err
+	}
+	key, err := f.keyFlags.resolve(nil, 0)
+	if err != nil {
+		return err
+	}
+	rp, err := loadPack(ctx, args[0])
+	if err != nil {
+		return err
+	}
+
+	res, err := rp.Trace(recipients, key)
+	if errors.Is(err, pack.ErrNoWatermark) {
+		return withExitCode(ExitCheckFailed, err)
+	} else if err != nil {
+		return err
+	}
+	ctx.Printf("Resource pack was built for %s (found in %s, confidence %.0f%%)\n", res.Recipient, res.Source, res.Confidence*100)
+	ctx.Result(map[string]any{"recipient": res.Recipient, "source": res.Source, "confidence": res.Confidence})
+	return nil
+}
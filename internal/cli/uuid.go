@@ -0,0 +1,156 @@
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/akmalfairuz/bedrockpack/pack"
+	"github.com/google/uuid"
+	"sort"
+	"strings"
+)
+
+type uuidFlags struct {
+	outputFlags
+	regenerate bool
+	seed       string
+	namespace  string
+	name       string
+}
+
+var uuidCommand = &Command{
+	Name:  "uuid",
+	Usage: "uuid [flags] <path to pack> [other packs to check for duplicates...]",
+	Short: "Show, check and regenerate the UUIDs of a pack",
+	Long: `Without flags, the header and module UUIDs are printed and checked for duplicates within the pack and
+against the other packs given. --regenerate, --seed and --namespace with --name generate new UUIDs first.
+The UUID in the contents.json header of an encrypted pack is updated as well.`,
+	MinArgs: 1,
+	Setup: func(fs *flag.FlagSet) RunFunc {
+		f := &uuidFlags{}
+		f.outputFlags.register(fs)
+		fs.BoolVar(&f.regenerate, "regenerate", false, "generate random UUIDs")
+		fs.StringVar(&f.seed, "seed", "", "generate UUIDs derived from the `seed` given")
+		fs.StringVar(&f.namespace, "namespace", "", "generate name-based UUIDs in the `namespace` given: a UUID, or dns, url, oid or x500")
+		fs.StringVar(&f.name, "name", "", "`name` to generate name-based UUIDs from")
+		return f.run
+	},
+}
+
+func (f *uuidFlags) run(ctx *Context, args []string) error {
+	rp, err := loadPack(ctx, args[0])
+	if err != nil {
+		return err
+	}
+
+	res := map[string]any{}
+	regenerated := true
+	switch {
+	case f.namespace != "" || f.name != "":
+		if f.namespace == "" || f.name == "" {
+			return withExitCode(ExitUsage, errors.New("--namespace and --name must be given together"))
+		}
+		namespace, err := parseNamespace(f.namespace)
+		if err != nil {
+			return withExitCode(ExitUsage, err)
+		}
+		if err := rp.RegenerateUUIDFromName(namespace, f.name); err != nil {
+			return err
+		}
+	case f.seed != "":
+		if err := rp.RegenerateUUID([]byte(f.seed)); err != nil {
+			return err
+		}
+	case f.regenerate:
+		if err := rp.RegenerateUUID(nil); err != nil {
+			return err
+		}
+	default:
+		regenerated = false
+	}
+	if regenerated {
+		output, err := f.outputFlags.save(ctx, rp, args[0])
+		if err != nil {
+			return err
+		}
+		ctx.Println("UUIDs regenerated!")
+		res["path"] = output
+	}
+
+	manifest, err := rp.Manifest()
+	if err != nil {
+		return err
+	}
+	modules := make([]string, 0, len(manifest.Modules))
+	ctx.Printf("Header: %s\n", manifest.Header.UUID)
+	for _, module := range manifest.Modules {
+		ctx.Printf("Module: %s (%s)\n", module.UUID, module.Type)
+		modules = append(modules, module.UUID)
+	}
+	res["uuid"], res["modules"] = manifest.Header.UUID, modules
+
+	// Map every UUID to the places it is used in, to find duplicates.
+	uses := map[string][]string{}
+	addUses := func(path string, m pack.Manifest) {
+		for i, id := range m.UUIDs() {
+			place := path + " header"
+			if i > 0 {
+				place = fmt.Sprintf("%s module %d", path, i-1)
+			}
+			uses[strings.ToLower(id)] = append(uses[strings.ToLower(id)], place)
+		}
+	}
+	addUses(args[0], manifest)
+	for _, other := range args[1:] {
+		otherPack, err := loadPack(ctx, other)
+		if err != nil {
+			return err
+		}
+		otherManifest, err := otherPack.Manifest()
+		if err != nil {
+			return fmt.Errorf("%s: %w", other, err)
+		}
+		addUses(other, otherManifest)
+	}
+
+	duplicates := map[string][]string{}
+	for id, places := range uses {
+		if len(places) > 1 {
+			duplicates[id] = places
+		}
+	}
+	res["duplicates"] = duplicates
+	ctx.Result(res)
+	if len(duplicates) == 0 {
+		ctx.Println("No duplicate UUIDs found.")
+		return nil
+	}
+	ids := make([]string, 0, len(duplicates))
+	for id := range duplicates {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		ctx.Printf("Duplicate UUID %s: %s\n", id, strings.Join(duplicates[id], ", "))
+	}
+	return withExitCode(ExitCheckFailed, fmt.Errorf("%d duplicate UUIDs found", len(duplicates)))
+}
+
+// parseNamespace parses a UUID namespace, either as a UUID or as the name of a predefined namespace.
+func parseNamespace(s string) (uuid.UUID, error) {
+	switch strings.ToLower(s) {
+	case "dns":
+		return uuid.NameSpaceDNS, nil
+	case "url":
+		return uuid.NameSpaceURL, nil
+	case "oid":
+		return uuid.NameSpaceOID, nil
+	case "x500":
+		return uuid.NameSpaceX500, nil
+	}
+	namespace, err := uuid.Parse(s)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("invalid namespace %q: must be a UUID, dns, url, oid or x500", s)
+	}
+	return namespace, nil
+}
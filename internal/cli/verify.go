@@ -0,0 +1,58 @@
+package cli
+
+import (
+	"errors"
+	"flag"
+)
+
+type verifyFlags struct {
+	keyFlags
+}
+
+var verifyCommand = &Command{
+	Name:  "verify",
+	Usage: "verify [flags] <path to resource pack> <key>",
+	Short: "Verify that an encrypted resource pack is consistent and decrypts with the given key",
+	Long: `Checks contents.json, that every file decrypts to plausible content and that the pack loads with the key.
+Exits with code 4 if the resource pack has problems.`,
+	MinArgs: 1,
+	Setup: func(fs *flag.FlagSet) RunFunc {
+		f := &verifyFlags{}
+		f.keyFlags.register(fs, false)
+		return f.run
+	},
+}
+
+func (f *verifyFlags) run(ctx *Context, args []string) error {
+	rp, err := loadPack(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	key, err := f.keyFlags.require(args, 1)
+	if err != nil {
+		return err
+	}
+
+	ctx.Println("Verifying resource pack with key " + string(key) + "...")
+	err = rp.Verify(key)
+	if err == nil {
+		ctx.Println("Resource pack verified!")
+		ctx.Result(map[string]any{"valid": true, "problems": []string{}})
+		return nil
+	}
+
+	var problems []string
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		for _, problem := range joined.Unwrap() {
+			problems = append(problems, problem.Error())
+		}
+	} else {
+		problems = append(problems, err.Error())
+	}
+	ctx.Result(map[string]any{"valid": false, "problems": problems})
+	ctx.Println("Resource pack verification failed:")
+	for _, problem := range problems {
+		ctx.Println("   " + problem)
+	}
+	return withExitCode(ExitCheckFailed, errors.New("resource pack verification failed"))
+}
@@ -3,6 +3,7 @@ package stealer
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/akmalfairuz/bedrockpack/pack"
 	"github.com/sandertv/gophertunnel/minecraft"
@@ -18,7 +19,16 @@ import (
 	"time"
 )
 
-func Run(serverAddress string) {
+// SavedPack is a resource pack of a server saved by Run.
+type SavedPack struct {
+	Name string `json:"name"`
+	Key  string `json:"key"`
+	Path string `json:"path"`
+}
+
+// Run connects to the server at the address given and saves every resource pack it sends, decrypted. Progress
+// and the prompt to authenticate are written to out. It returns the packs saved, also if an error occurred.
+func Run(serverAddress string, out io.Writer) ([]SavedPack, error) {
 	if len(strings.Split(serverAddress, ":")) == 1 {
 		serverAddress = serverAddress + ":19132"
 	}
@@ -27,28 +37,30 @@ func Run(serverAddress string) {
 		var cacheTok *oauth2.Token
 		if err := json.Unmarshal(cacheTokenBytes, &cacheTok); err == nil {
 			if time.Now().Add(time.Second * 30).Before(cacheTok.Expiry) {
-				fmt.Println("Using .token_cache for authentication")
+				_, _ = fmt.Fprintln(out, "Using .token_cache for authentication")
 				src := auth.RefreshTokenSource(cacheTok)
-				handleConn(serverAddress, src)
-				return
+				return handleConn(serverAddress, src, out)
 			}
 		}
 	}
 
-	token, err := auth.RequestLiveToken()
+	token, err := auth.RequestLiveTokenWriter(out)
 	if err != nil {
-		panic(err)
+		return nil, fmt.Errorf("request xbox live token: %w", err)
 	}
 	tokBytes, err := json.Marshal(token)
+	if err != nil {
+		return nil, err
+	}
 	if err := os.WriteFile(".token_cache", tokBytes, 0777); err != nil {
-		panic(err)
+		return nil, err
 	}
 
 	src := auth.RefreshTokenSource(token)
-	handleConn(serverAddress, src)
+	return handleConn(serverAddress, src, out)
 }
 
-func handleConn(serverAddress string, src oauth2.TokenSource) {
+func handleConn(serverAddress string, src oauth2.TokenSource, out io.Writer) ([]SavedPack, error) {
 	ctx, cancel := context.WithCancel(context.Background())
 	sigs := make(chan os.Signal, 1)
 	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
@@ -64,40 +76,50 @@ func handleConn(serverAddress string, src oauth2.TokenSource) {
 		os.Exit(0)
 	}()
 
-	fmt.Printf("Connecting to %s... (may take up to 5 minutes) \n", serverAddress)
+	_, _ = fmt.Fprintf(out, "Connecting to %s... (may take up to 5 minutes) \n", serverAddress)
 	serverConn, err = minecraft.Dialer{
 		TokenSource: src,
 	}.DialContext(ctx, "raknet", serverAddress)
 	if err != nil {
-		panic(err)
+		return nil, fmt.Errorf("connect to %s: %w", serverAddress, err)
 	}
+	defer serverConn.Close()
 
-	fmt.Println("Getting resource pack information...")
+	_, _ = fmt.Fprintln(out, "Getting resource pack information...")
 	if err := serverConn.DoSpawnContext(ctx); err != nil {
-		panic(err)
+		return nil, fmt.Errorf("spawn on %s: %w", serverAddress, err)
 	}
 
+	saved := make([]SavedPack, 0, len(serverConn.ResourcePacks()))
 	for i, rp := range serverConn.ResourcePacks() {
-		if err := stealPack(i, serverAddress, rp); err != nil {
-			panic(err)
+		savePath, err := stealPack(i, serverAddress, rp, out)
+		if err != nil {
+			return saved, err
 		}
+		saved = append(saved, SavedPack{Name: rp.Name(), Key: rp.ContentKey(), Path: savePath})
 	}
-
-	_ = serverConn.Close()
+	return saved, nil
 }
 
-func stealPack(i int, serverAddress string, rp *resource.Pack) error {
-	packBytes, err := downloadPack(rp)
+func stealPack(i int, serverAddress string, rp *resource.Pack, out io.Writer) (string, error) {
+	packBytes, err := downloadPack(rp, out)
 	if err != nil {
-		return err
+		return "", err
 	}
 	pac, err := pack.LoadResourcePackFromBytes(packBytes)
 	if err != nil {
-		return fmt.Errorf("error loading resource pack: %w", err)
+		return "", fmt.Errorf("error loading resource pack: %w", err)
 	}
-	fmt.Printf("Decrypting resource pack %s with key %s ...\n", rp.Name(), rp.ContentKey())
+	_, _ = fmt.Fprintf(out, "Decrypting resource pack %s with key %s ...\n", rp.Name(), rp.ContentKey())
 	if err := pac.Decrypt([]byte(rp.ContentKey())); err != nil {
-		return fmt.Errorf("error when decrypting resource pack: %w", err)
+		var decryptErr *pack.DecryptError
+		switch {
+		case errors.Is(err, pack.ErrNotEncrypted):
+		case errors.As(err, &decryptErr):
+			_, _ = fmt.Fprintf(out, "Warning: %s\n", err)
+		default:
+			return "", fmt.Errorf("error when decrypting resource pack: %w", err)
+		}
 	}
 
 	rpName := rp.Name()
@@ -110,13 +132,13 @@ func stealPack(i int, serverAddress string, rp *resource.Pack) error {
 	_ = os.Mkdir(prefix, 0777)
 	savePath := fmt.Sprintf("%s/%d_%s.zip", prefix, i, rpName)
 
-	fmt.Printf("Resource pack saved in %s\n", savePath)
-	return pac.Save(savePath)
+	_, _ = fmt.Fprintf(out, "Resource pack saved in %s\n", savePath)
+	return savePath, pac.Save(savePath)
 }
 
-func downloadPack(pack *resource.Pack) ([]byte, error) {
+func downloadPack(pack *resource.Pack, out io.Writer) ([]byte, error) {
 	if pack.DownloadURL() != "" {
-		fmt.Printf("Downloading resource pack %s from %s\n", pack.Name(), pack.DownloadURL())
+		_, _ = fmt.Fprintf(out, "Downloading resource pack %s from %s\n", pack.Name(), pack.DownloadURL())
 		resp, err := http.Get(pack.DownloadURL())
 		if err != nil {
 			return nil, err
@@ -0,0 +1,83 @@
+package pack
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+// LoadAddon loads every pack in the .mcaddon archive at the path given. An add-on holds its packs either as
+// nested .mcpack archives or as top-level directories.
+func LoadAddon(path string) ([]*ResourcePack, error) {
+	addonBytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return LoadAddonFromBytes(addonBytes)
+}
+
+// LoadAddonFromBytes loads every pack in the .mcaddon archive passed.
+func LoadAddonFromBytes(addonBytes []byte) ([]*ResourcePack, error) {
+	reader, err := zip.NewReader(bytes.NewReader(addonBytes), int64(len(addonBytes)))
+	if err != nil {
+		return nil, err
+	}
+
+	var packs []*ResourcePack
+	dirs := map[string]map[string][]byte{}
+	for _, fileInfo := range reader.File {
+		if strings.HasSuffix(fileInfo.Name, "/") {
+			continue
+		}
+		file, err := fileInfo.Open()
+		if err != nil {
+			return nil, err
+		}
+		content, err := io.ReadAll(file)
+		_ = file.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		switch ext := strings.ToLower(path.Ext(fileInfo.Name)); {
+		case ext == ".mcpack" || ext == ".zip":
+			rp, err := LoadResourcePackFromBytes(content)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", fileInfo.Name, err)
+			}
+			packs = append(packs, rp)
+		default:
+			dir, _, _ := strings.Cut(fileInfo.Name, "/")
+			if dirs[dir] == nil {
+				dirs[dir] = map[string][]byte{}
+			}
+			dirs[dir][fileInfo.Name] = content
+		}
+	}
+
+	dirNames := make([]string, 0, len(dirs))
+	for dir := range dirs {
+		dirNames = append(dirNames, dir)
+	}
+	sort.Strings(dirNames)
+	for _, dir := range dirNames {
+		if _, ok := dirs[dir][dir+"/manifest.json"]; !ok {
+			continue
+		}
+		rp := &ResourcePack{}
+		if err := rp.loadFiles(dirs[dir]); err != nil {
+			return nil, fmt.Errorf("%s: %w", dir, err)
+		}
+		packs = append(packs, rp)
+	}
+	if len(packs) == 0 {
+		return nil, errors.New("no packs found in add-on")
+	}
+	return packs, nil
+}
@@ -0,0 +1,130 @@
+package pack
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"io"
+)
+
+// cfb8BufferSize is the size of the buffer holding the shift register. The register slides through the
+// buffer and is only moved back to the start once the end is reached, so that not every byte requires a copy.
+const cfb8BufferSize = 4096
+
+// cfb8 implements cipher.Stream for AES in CFB mode with an 8-bit segment size, which is the mode used by
+// Minecraft for encrypted packs.
+type cfb8 struct {
+	b       cipher.Block
+	buf     []byte
+	off     int
+	out     []byte
+	decrypt bool
+}
+
+// NewCFB8Encrypter returns a cipher.Stream which encrypts with CFB8 using the block and iv given. The length
+// of iv must be the same as the block size of the block.
+func NewCFB8Encrypter(block cipher.Block, iv []byte) cipher.Stream {
+	return newCFB8(block, iv, false)
+}
+
+// NewCFB8Decrypter returns a cipher.Stream which decrypts with CFB8 using the block and iv given. The length
+// of iv must be the same as the block size of the block.
+func NewCFB8Decrypter(block cipher.Block, iv []byte) cipher.Stream {
+	return newCFB8(block, iv, true)
+}
+
+func newCFB8(block cipher.Block, iv []byte, decrypt bool) *cfb8 {
+	if len(iv) != block.BlockSize() {
+		panic("pack: iv length must equal block size")
+	}
+	s := &cfb8{
+		b:       block,
+		buf:     make([]byte, cfb8BufferSize+block.BlockSize()),
+		out:     make([]byte, block.BlockSize()),
+		decrypt: decrypt,
+	}
+	copy(s.buf, iv)
+	return s
+}
+
+// XORKeyStream ...
+func (s *cfb8) XORKeyStream(dst, src []byte) {
+	if len(dst) < len(src) {
+		panic("pack: output smaller than input")
+	}
+	b, buf, out, off := s.b, s.buf, s.out, s.off
+	blockSize := b.BlockSize()
+	for len(src) > 0 {
+		if off == cfb8BufferSize {
+			copy(buf, buf[off:])
+			off = 0
+		}
+		var n int
+		if s.decrypt {
+			// The ciphertext is known up front when decrypting, so it is copied into the buffer in one go.
+			// This also keeps decryption in place safe, as src is not read after dst is written.
+			n = copy(buf[off+blockSize:], src)
+			for i := 0; i < n; i++ {
+				b.Encrypt(out, buf[off+i:off+i+blockSize])
+				dst[i] = buf[off+i+blockSize] ^ out[0]
+			}
+		} else {
+			n = min(len(src), cfb8BufferSize-off)
+			for i := 0; i < n; i++ {
+				b.Encrypt(out, buf[off+i:off+i+blockSize])
+				dst[i] = src[i] ^ out[0]
+				buf[off+i+blockSize] = dst[i]
+			}
+		}
+		off += n
+		dst, src = dst[n:], src[n:]
+	}
+	s.off = off
+}
+
+// newPackStream returns the CFB8 stream used for pack files encrypted with the key given. The first 16 bytes
+// of the key are used as the iv.
+func newPackStream(key []byte, decrypt bool) (cipher.Stream, error) {
+	b, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return newCFB8(b, key[:aes.BlockSize], decrypt), nil
+}
+
+// NewEncryptReader returns a reader that encrypts data read from r with the key given.
+func NewEncryptReader(r io.Reader, key []byte) (io.Reader, error) {
+	s, err := newPackStream(key, false)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.StreamReader{S: s, R: r}, nil
+}
+
+// NewDecryptReader returns a reader that decrypts data read from r with the key given.
+func NewDecryptReader(r io.Reader, key []byte) (io.Reader, error) {
+	s, err := newPackStream(key, true)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.StreamReader{S: s, R: r}, nil
+}
+
+// NewEncryptWriter returns a writer that encrypts data with the key given before writing it to w. Closing the
+// writer closes w if it implements io.Closer.
+func NewEncryptWriter(w io.Writer, key []byte) (io.WriteCloser, error) {
+	s, err := newPackStream(key, false)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.StreamWriter{S: s, W: w}, nil
+}
+
+// NewDecryptWriter returns a writer that decrypts data with the key given before writing it to w. Closing the
+// writer closes w if it implements io.Closer.
+func NewDecryptWriter(w io.Writer, key []byte) (io.WriteCloser, error) {
+	s, err := newPackStream(key, true)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.StreamWriter{S: s, W: w}, nil
+}
@@ -0,0 +1,22 @@
+package pack
+
+import "time"
+
+// clock is the source of time of OTF. It is replaced in tests to control when updates are checked.
+type clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+// realClock is the clock that uses the time package.
+type realClock struct{}
+
+// Now ...
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+// After ...
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
@@ -0,0 +1,32 @@
+package pack
+
+import (
+	"bytes"
+	"path"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	pngMagic = []byte("\x89PNG\r\n\x1a\n")
+	utf8BOM  = []byte("\xef\xbb\xbf")
+)
+
+// plausibleContent reports whether data looks like a valid file of the type implied by the extension of
+// fileName. Files with extensions that cannot be checked are always considered plausible.
+func plausibleContent(fileName string, data []byte) bool {
+	switch strings.ToLower(path.Ext(fileName)) {
+	case ".png":
+		return bytes.HasPrefix(data, pngMagic)
+	case ".json":
+		data = bytes.TrimSpace(bytes.TrimPrefix(data, utf8BOM))
+		if len(data) == 0 {
+			return true
+		}
+		// JSON files in packs may start with comments, so only the first character is checked.
+		return data[0] == '{' || data[0] == '[' || data[0] == '/'
+	case ".lang":
+		return utf8.Valid(data)
+	}
+	return true
+}
@@ -0,0 +1,188 @@
+package pack
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+type contentJson struct {
+	Content []contentJsonEntry `json:"content"`
+}
+
+type contentJsonEntry struct {
+	Path string `json:"path"`
+	Key  string `json:"key"`
+}
+
+// contentsHeaderSize is the size of the unencrypted header that precedes the encrypted body of contents.json.
+const contentsHeaderSize = 256
+
+// contentsScope is a part of a pack that is described by its own contents.json. The root of the pack is a
+// scope, and so is every subpack declared in the manifest.
+type contentsScope struct {
+	// prefix is the folder of the scope, ending with a slash, or empty for the root of the pack.
+	prefix string
+	// subpacks holds the prefixes of the subpacks, which are excluded from the root scope.
+	subpacks []string
+}
+
+// contains reports whether the file with the name given belongs to the scope.
+func (s contentsScope) contains(fileName string) bool {
+	if !strings.HasPrefix(fileName, s.prefix) {
+		return false
+	}
+	for _, subpack := range s.subpacks {
+		if strings.HasPrefix(fileName, subpack) {
+			return false
+		}
+	}
+	return true
+}
+
+// contentsScopes returns the root scope of the pack followed by a scope for every subpack in the manifest.
+func (r *ResourcePack) contentsScopes() []contentsScope {
+	subpacks := r.subpackFolders()
+	scopes := []contentsScope{{subpacks: subpacks}}
+	for _, subpack := range subpacks {
+		scopes = append(scopes, contentsScope{prefix: subpack})
+	}
+	return scopes
+}
+
+// subpackFolders returns the folders of the subpacks declared in the manifest, such as "subpacks/low/".
+func (r *ResourcePack) subpackFolders() []string {
+	return subpackFolders(r.files["manifest.json"])
+}
+
+// subpackFolders returns the folders of the subpacks declared in the manifest passed.
+func subpackFolders(manifestBytes []byte) []string {
+	var manifest struct {
+		Subpacks []struct {
+			FolderName string `json:"folder_name"`
+		} `json:"subpacks"`
+	}
+	if err := json.Unmarshal(manifestBytes, &manifest); err != nil {
+		return nil
+	}
+	var folders []string
+	for _, subpack := range manifest.Subpacks {
+		if subpack.FolderName == "" {
+			continue
+		}
+		folders = append(folders, "subpacks/"+strings.Trim(subpack.FolderName, "/")+"/")
+	}
+	return folders
+}
+
+// readContents decrypts the contents.json of the scope given with the key passed. It returns ErrWrongKey if
+// the body of contents.json does not decrypt to valid JSON.
+func (r *ResourcePack) readContents(scope contentsScope, key []byte) ([]contentJsonEntry, error) {
+	contentsBytes, err := r.loadFile(scope.prefix + "contents.json")
+	if err != nil {
+		return nil, err
+	}
+	entries, err := decryptContents(contentsBytes, key)
+	if err != nil && scope.prefix != "" {
+		return nil, fmt.Errorf("%scontents.json: %w", scope.prefix, err)
+	}
+	return entries, err
+}
+
+// decryptContents decrypts the contents.json passed with the key given. The data passed is not modified.
+func decryptContents(contentsBytes []byte, key []byte) ([]contentJsonEntry, error) {
+	if len(contentsBytes) < contentsHeaderSize {
+		return nil, fmt.Errorf("contents.json bytes is less than %d bytes", contentsHeaderSize)
+	}
+
+	// contents.json is decrypted in a copy so that a wrong key leaves the pack untouched.
+	decryptedContents, err := decryptCfb(bytes.Clone(contentsBytes[contentsHeaderSize:]), key)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrWrongKey, err)
+	}
+
+	var contents contentJson
+	if err := json.Unmarshal(decryptedContents, &contents); err != nil {
+		return nil, ErrWrongKey
+	}
+	return contents.Content, nil
+}
+
+// contentsMagic is the magic that follows the version in the header of contents.json.
+const contentsMagic = "\xfc\xb9\xcf\x9b"
+
+// parseContentsHeader validates the unencrypted header of contents.json and returns the pack UUID stored in it.
+func parseContentsHeader(contentsBytes []byte) (string, error) {
+	if len(contentsBytes) < contentsHeaderSize {
+		return "", fmt.Errorf("%w: less than %d bytes", ErrInvalidHeader, contentsHeaderSize)
+	}
+	if string(contentsBytes[4:8]) != contentsMagic {
+		return "", fmt.Errorf("%w: unknown magic %x", ErrInvalidHeader, contentsBytes[4:8])
+	}
+	uuidLen := int(contentsBytes[16])
+	if 17+uuidLen > contentsHeaderSize {
+		return "", fmt.Errorf("%w: uuid length %d out of range", ErrInvalidHeader, uuidLen)
+	}
+	return string(contentsBytes[17 : 17+uuidLen]), nil
+}
+
+// writeContents encrypts the entries given with the key passed and writes them to the contents.json of the
+// scope given.
+func (r *ResourcePack) writeContents(scope contentsScope, entries []contentJsonEntry, key []byte) error {
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Path < entries[j].Path
+	})
+	contentBytes, err := json.Marshal(contentJson{Content: entries})
+	if err != nil {
+		return err
+	}
+
+	contentBytes2 := bytes.NewBuffer(nil)
+	contentBytes2.Write(make([]byte, 4))     // version
+	contentBytes2.WriteString(contentsMagic) // type
+	contentBytes2.Write(make([]byte, 8))     // padding
+	contentBytes2.WriteString("\x24")        // separator
+	contentBytes2.WriteString(r.uuid)        // uuid
+	contentBytes2.Write(make([]byte, contentsHeaderSize-contentBytes2.Len()))
+	if r.watermark != nil {
+		copy(contentBytes2.Bytes()[watermarkOffset:], r.watermark)
+	}
+
+	encryptedContentBytes, err := encryptCfb(contentBytes, key)
+	if err != nil {
+		return err
+	}
+	contentBytes2.Write(encryptedContentBytes)
+
+	r.files[scope.prefix+"contents.json"] = contentBytes2.Bytes()
+	return nil
+}
+
+// rewriteContentsUUID writes the UUID of the pack to the header of every contents.json of an encrypted pack,
+// leaving the rest of the header and the encrypted body untouched.
+func (r *ResourcePack) rewriteContentsUUID() error {
+	if !r.encrypted {
+		return nil
+	}
+	if 17+len(r.uuid) > watermarkOffset {
+		return fmt.Errorf("uuid %q too long for contents.json header", r.uuid)
+	}
+	for _, scope := range r.contentsScopes() {
+		name := scope.prefix + "contents.json"
+		contentsBytes, ok := r.files[name]
+		if !ok {
+			continue
+		}
+		if _, err := parseContentsHeader(contentsBytes); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		contentsBytes = bytes.Clone(contentsBytes)
+		contentsBytes[16] = byte(len(r.uuid))
+		clear(contentsBytes[17:watermarkOffset])
+		copy(contentsBytes[17:], r.uuid)
+		r.files[name] = contentsBytes
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package pack
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"slices"
+	"strings"
+)
+
+// AddFile adds the file with the name and content given to the pack, replacing any existing file with that
+// name. If the pack is encrypted, the file is listed in the contents.json it belongs to, for which key must be
+// the key of the pack, and encrypted with a new key, unless it replaces a file listed without a key.
+// ErrKeyRequired is returned if no key is given. The name must be a valid path within the pack, as reported by
+// fs.ValidPath. The content passed is not modified.
+func (r *ResourcePack) AddFile(name string, content []byte, key []byte) error {
+	name = strings.ReplaceAll(name, "\\", "/")
+	if !fs.ValidPath(name) || name == "." {
+		return fmt.Errorf("invalid file name %q", name)
+	}
+	if !r.encrypted {
+		r.files[name] = content
+		return nil
+	}
+	if len(key) == 0 {
+		return ErrKeyRequired
+	}
+
+	scope := r.scopeOf(name)
+	if strings.TrimPrefix(name, scope.prefix) == "contents.json" {
+		return fmt.Errorf("%s: cannot replace contents.json of an encrypted pack", name)
+	}
+	entries, err := r.readContents(scope, key)
+	if err != nil {
+		return err
+	}
+	// A file that is replaced stays encrypted or unencrypted as it was listed, as it may have been left
+	// unencrypted on purpose.
+	encrypt := (EncryptOptions{}).encrypts(name)
+	entries = slices.DeleteFunc(entries, func(entry contentJsonEntry) bool {
+		if scope.prefix+entry.Path != name {
+			return false
+		}
+		encrypt = entry.Key != ""
+		return true
+	})
+
+	entry := contentJsonEntry{Path: strings.TrimPrefix(name, scope.prefix)}
+	if encrypt {
+		fileKey := GenerateKey()
+		if content, err = encryptCfb(bytes.Clone(content), fileKey); err != nil {
+			return err
+		}
+		entry.Key = string(fileKey)
+	}
+	if err := r.writeContents(scope, append(entries, entry), key); err != nil {
+		return err
+	}
+	r.files[name] = content
+	return nil
+}
+
+// RemoveFile removes the file with the name given from the pack. If the pack is encrypted, the file is also
+// removed from the contents.json it is listed in, for which key must be the key of the pack. ErrKeyRequired is
+// returned if no key is given.
+func (r *ResourcePack) RemoveFile(name string, key []byte) error {
+	name = strings.TrimPrefix(strings.ReplaceAll(name, "\\", "/"), "/")
+	if _, ok := r.files[name]; !ok {
+		return fmt.Errorf("file %s not found", name)
+	}
+	if !r.encrypted {
+		delete(r.files, name)
+		return nil
+	}
+	if len(key) == 0 {
+		return ErrKeyRequired
+	}
+
+	scope := r.scopeOf(name)
+	if strings.TrimPrefix(name, scope.prefix) == "contents.json" {
+		return fmt.Errorf("%s: cannot remove contents.json of an encrypted pack", name)
+	}
+	entries, err := r.readContents(scope, key)
+	if err != nil {
+		return err
+	}
+	entries = slices.DeleteFunc(entries, func(entry contentJsonEntry) bool {
+		return scope.prefix+entry.Path == name
+	})
+	if err := r.writeContents(scope, entries, key); err != nil {
+		return err
+	}
+	delete(r.files, name)
+	return nil
+}
+
+// scopeOf returns the contents scope that the file with the name given belongs to.
+func (r *ResourcePack) scopeOf(name string) contentsScope {
+	scopes := r.contentsScopes()
+	for _, scope := range scopes[1:] {
+		if scope.contains(name) {
+			if _, ok := r.files[scope.prefix+"contents.json"]; ok {
+				return scope
+			}
+		}
+	}
+	return scopes[0]
+}
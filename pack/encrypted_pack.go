@@ -0,0 +1,338 @@
+package pack
+
+import (
+	"archive/zip"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// EncryptedPack is a read-only view of a pack archive or directory that decrypts files lazily. Only contents.json is
+// decrypted when the pack is opened, and every other file is decrypted as it is read, so memory use stays
+// proportional to the file being read. EncryptedPack implements fs.FS and fs.ReadDirFS.
+type EncryptedPack struct {
+	closer io.Closer
+	uuid   string
+	files  map[string]packFile
+	keys   map[string]string
+	dirs   map[string][]fs.DirEntry
+}
+
+// OpenEncryptedPack opens the pack archive or directory at the path given and decrypts its contents.json with
+// the key passed. The EncryptedPack must be closed after use.
+func OpenEncryptedPack(path string, key []byte) (*EncryptedPack, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	stat, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	if stat.IsDir() {
+		_ = f.Close()
+		return openEncryptedPackDir(path, key)
+	}
+	p, err := NewEncryptedPack(f, stat.Size(), key)
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	p.closer = f
+	return p, nil
+}
+
+// NewEncryptedPack reads the pack archive of the size given from r and decrypts its contents.json with the key
+// passed. Packs that are not encrypted may be read too, in which case the key is not used. If the key is empty,
+// contents.json is not decrypted and every file is read as it is stored in the archive.
+func NewEncryptedPack(r io.ReaderAt, size int64, key []byte) (*EncryptedPack, error) {
+	reader, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+	files := make(map[string]packFile, len(reader.File))
+	for _, f := range reader.File {
+		files[f.Name] = f
+	}
+	return newEncryptedPack(files, key)
+}
+
+// openEncryptedPackDir opens the pack in the directory at the path given and decrypts its contents.json with
+// the key passed. Files are read from the directory as they are opened.
+func openEncryptedPackDir(dir string, key []byte) (*EncryptedPack, error) {
+	files := map[string]packFile{}
+	err := filepath.WalkDir(dir, func(filePath string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, filePath)
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = dirPackFile{path: filePath, info: info}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return newEncryptedPack(files, key)
+}
+
+// newEncryptedPack returns an EncryptedPack of the files passed, keyed by their path, and decrypts its
+// contents.json with the key given.
+func newEncryptedPack(files map[string]packFile, key []byte) (*EncryptedPack, error) {
+	// The manifest closest to the root of the pack decides the base path, similar to ResourcePack.
+	basePath, depth := "", -1
+	for name := range files {
+		if path.Base(name) != "manifest.json" {
+			continue
+		}
+		if d := strings.Count(name, "/"); depth == -1 || d < depth {
+			basePath, depth = strings.TrimSuffix(name, "manifest.json"), d
+		}
+	}
+	if depth == -1 {
+		return nil, errors.New("manifest.json not found")
+	}
+
+	p := &EncryptedPack{
+		files: map[string]packFile{},
+		keys:  map[string]string{},
+		dirs:  map[string][]fs.DirEntry{},
+	}
+	for fileName, f := range files {
+		name := strings.TrimPrefix(fileName, basePath)
+		if !strings.HasPrefix(fileName, basePath) || strings.HasSuffix(name, "/") || !fs.ValidPath(name) {
+			continue
+		}
+		p.files[name] = f
+	}
+
+	manifestBytes, err := p.readRaw("manifest.json")
+	if err != nil {
+		return nil, err
+	}
+	if p.uuid, err = manifestUUID(manifestBytes); err != nil {
+		return nil, err
+	}
+
+	prefixes := append([]string{""}, subpackFolders(manifestBytes)...)
+	for _, prefix := range prefixes {
+		if _, ok := p.files[prefix+"contents.json"]; !ok || len(key) == 0 {
+			continue
+		}
+		contentsBytes, err := p.readRaw(prefix + "contents.json")
+		if err != nil {
+			return nil, err
+		}
+		entries, err := decryptContents(contentsBytes, key)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			if entry.Key != "" {
+				p.keys[prefix+entry.Path] = entry.Key
+			}
+		}
+	}
+	p.buildDirs()
+	return p, nil
+}
+
+// UUID returns the UUID of the pack as found in manifest.json.
+func (p *EncryptedPack) UUID() string {
+	return p.uuid
+}
+
+// FileNames returns the names of all files in the pack, sorted.
+func (p *EncryptedPack) FileNames() []string {
+	fileNames := make([]string, 0, len(p.files))
+	for fileName := range p.files {
+		fileNames = append(fileNames, fileName)
+	}
+	sort.Strings(fileNames)
+	return fileNames
+}
+
+// Encrypted reports whether the file with the name given is encrypted in the archive.
+func (p *EncryptedPack) Encrypted(name string) bool {
+	_, ok := p.keys[name]
+	return ok
+}
+
+// Open opens the file with the name given. If the file is encrypted, it is decrypted while it is read.
+func (p *EncryptedPack) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	if entries, ok := p.dirs[name]; ok {
+		return &encryptedPackDir{info: dirInfo(name), entries: entries}, nil
+	}
+	f, ok := p.files[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	var r io.Reader = rc
+	if key, ok := p.keys[name]; ok {
+		if r, err = NewDecryptReader(rc, []byte(key)); err != nil {
+			_ = rc.Close()
+			return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+		}
+	}
+	return &encryptedPackFile{Reader: r, closer: rc, info: f.FileInfo()}, nil
+}
+
+// ReadDir ...
+func (p *EncryptedPack) ReadDir(name string) ([]fs.DirEntry, error) {
+	entries, ok := p.dirs[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+	return append([]fs.DirEntry(nil), entries...), nil
+}
+
+// Close closes the underlying archive file if the pack was opened using OpenEncryptedPack.
+func (p *EncryptedPack) Close() error {
+	if p.closer == nil {
+		return nil
+	}
+	return p.closer.Close()
+}
+
+// readRaw reads the file with the name given without decrypting it.
+func (p *EncryptedPack) readRaw(name string) ([]byte, error) {
+	f, ok := p.files[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
+
+// buildDirs builds the directory tree of the pack from the names of its files.
+func (p *EncryptedPack) buildDirs() {
+	p.dirs["."] = nil
+	seen := map[string]struct{}{}
+	for _, name := range p.FileNames() {
+		p.addDirEntry(path.Dir(name), fs.FileInfoToDirEntry(p.files[name].FileInfo()))
+		for dir := path.Dir(name); dir != "."; dir = path.Dir(dir) {
+			if _, ok := seen[dir]; ok {
+				break
+			}
+			seen[dir] = struct{}{}
+			p.addDirEntry(path.Dir(dir), fs.FileInfoToDirEntry(dirInfo(dir)))
+		}
+	}
+	for _, entries := range p.dirs {
+		sort.Slice(entries, func(i, j int) bool {
+			return entries[i].Name() < entries[j].Name()
+		})
+	}
+}
+
+func (p *EncryptedPack) addDirEntry(dir string, entry fs.DirEntry) {
+	p.dirs[dir] = append(p.dirs[dir], entry)
+}
+
+// packFile is a file of an EncryptedPack as it is stored, either in an archive or in a directory.
+type packFile interface {
+	Open() (io.ReadCloser, error)
+	FileInfo() fs.FileInfo
+}
+
+// dirPackFile is a file of a pack in a directory.
+type dirPackFile struct {
+	path string
+	info fs.FileInfo
+}
+
+// Open ...
+func (f dirPackFile) Open() (io.ReadCloser, error) {
+	return os.Open(f.path)
+}
+
+// FileInfo ...
+func (f dirPackFile) FileInfo() fs.FileInfo {
+	return f.info
+}
+
+// encryptedPackFile is a file of an EncryptedPack, decrypted while it is read.
+type encryptedPackFile struct {
+	io.Reader
+	closer io.Closer
+	info   fs.FileInfo
+}
+
+// Stat ...
+func (f *encryptedPackFile) Stat() (fs.FileInfo, error) {
+	return f.info, nil
+}
+
+// Close ...
+func (f *encryptedPackFile) Close() error {
+	return f.closer.Close()
+}
+
+// encryptedPackDir is a directory of an EncryptedPack.
+type encryptedPackDir struct {
+	info    fs.FileInfo
+	entries []fs.DirEntry
+	off     int
+}
+
+// Stat ...
+func (d *encryptedPackDir) Stat() (fs.FileInfo, error) {
+	return d.info, nil
+}
+
+// Read ...
+func (d *encryptedPackDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.info.Name(), Err: errors.New("is a directory")}
+}
+
+// Close ...
+func (d *encryptedPackDir) Close() error {
+	return nil
+}
+
+// ReadDir ...
+func (d *encryptedPackDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	entries := d.entries[d.off:]
+	if n > 0 {
+		if len(entries) == 0 {
+			return nil, io.EOF
+		}
+		entries = entries[:min(n, len(entries))]
+	}
+	d.off += len(entries)
+	return entries, nil
+}
+
+// dirInfo is the fs.FileInfo of a directory of an EncryptedPack.
+type dirInfo string
+
+func (d dirInfo) Name() string       { return path.Base(string(d)) }
+func (d dirInfo) Size() int64        { return 0 }
+func (d dirInfo) Mode() fs.FileMode  { return fs.ModeDir | 0555 }
+func (d dirInfo) ModTime() time.Time { return time.Time{} }
+func (d dirInfo) IsDir() bool        { return true }
+func (d dirInfo) Sys() any           { return nil }
@@ -0,0 +1,65 @@
+package pack
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+)
+
+func TestEncryptedPack(t *testing.T) {
+	key := []byte("0123Z5678K0123u567890123Z56789P1")
+	files := testPackFiles()
+	rp := newTestPack(t, testPackFiles())
+	if err := rp.Encrypt(key); err != nil {
+		t.Fatal(err)
+	}
+	packBytes, err := rp.SaveToBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := NewEncryptedPack(bytes.NewReader(packBytes), int64(len(packBytes)), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for fileName, fileBytes := range files {
+		content, err := fs.ReadFile(p, fileName)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(content, fileBytes) {
+			t.Errorf("%s: content mismatch", fileName)
+		}
+	}
+	if err := fstest.TestFS(p, "manifest.json", "textures/blocks/stone.png", "texts/en_US.lang"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewEncryptedPack(bytes.NewReader(packBytes), int64(len(packBytes)), []byte("aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa")); !errors.Is(err, ErrWrongKey) {
+		t.Fatalf("expected ErrWrongKey, got %v", err)
+	}
+
+	dir := t.TempDir()
+	if err := rp.SaveToDir(dir); err != nil {
+		t.Fatal(err)
+	}
+	dirPack, err := OpenEncryptedPack(dir, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dirPack.Close()
+	for fileName, fileBytes := range files {
+		content, err := fs.ReadFile(dirPack, fileName)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(content, fileBytes) {
+			t.Errorf("%s: content mismatch in directory", fileName)
+		}
+	}
+	if err := fstest.TestFS(dirPack, "manifest.json", "textures/blocks/stone.png", "texts/en_US.lang"); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -1,44 +1,24 @@
 package pack
 
-import (
-	"crypto/aes"
-	"math/rand"
-)
+import "math/rand"
 
+// decryptCfb decrypts data in place with the key given and returns it.
 func decryptCfb(data []byte, key []byte) ([]byte, error) {
-	b, err := aes.NewCipher(key)
+	s, err := newPackStream(key, true)
 	if err != nil {
 		return nil, err
 	}
-
-	shiftRegister := append(key[:16], data...) // prefill with iv + cipherdata
-	_tmp := make([]byte, 16)
-	off := 0
-	for off < len(data) {
-		b.Encrypt(_tmp, shiftRegister)
-		data[off] ^= _tmp[0]
-		shiftRegister = shiftRegister[1:]
-		off++
-	}
+	s.XORKeyStream(data, data)
 	return data, nil
 }
 
+// encryptCfb encrypts data in place with the key given and returns it.
 func encryptCfb(data []byte, key []byte) ([]byte, error) {
-	b, err := aes.NewCipher(key)
+	s, err := newPackStream(key, false)
 	if err != nil {
 		return nil, err
 	}
-
-	shiftRegister := make([]byte, 16)
-	copy(shiftRegister, key[:16]) // prefill with iv
-	_tmp := make([]byte, 16)
-	off := 0
-	for off < len(data) {
-		b.Encrypt(_tmp, shiftRegister)
-		data[off] ^= _tmp[0]
-		shiftRegister = append(shiftRegister[1:], data[off])
-		off++
-	}
+	s.XORKeyStream(data, data)
 	return data, nil
 }
 
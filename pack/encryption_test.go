@@ -2,8 +2,12 @@ package pack
 
 import (
 	"bytes"
+	"crypto/aes"
 	"encoding/hex"
+	"io"
+	"math/rand"
 	"testing"
+	"testing/iotest"
 )
 
 func TestEncrypt(t *testing.T) {
@@ -55,3 +59,135 @@ func TestEncryptDecryptCfb(t *testing.T) {
 		t.Fatal("mismatch")
 	}
 }
+
+// legacyEncryptCfb is the previous byte-by-byte implementation of encryptCfb, kept for comparison.
+func legacyEncryptCfb(data []byte, key []byte) ([]byte, error) {
+	b, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	shiftRegister := make([]byte, 16)
+	copy(shiftRegister, key[:16]) // prefill with iv
+	_tmp := make([]byte, 16)
+	off := 0
+	for off < len(data) {
+		b.Encrypt(_tmp, shiftRegister)
+		data[off] ^= _tmp[0]
+		shiftRegister = append(shiftRegister[1:], data[off])
+		off++
+	}
+	return data, nil
+}
+
+// legacyDecryptCfb is the previous byte-by-byte implementation of decryptCfb, kept for comparison.
+func legacyDecryptCfb(data []byte, key []byte) ([]byte, error) {
+	b, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	shiftRegister := append(bytes.Clone(key[:16]), data...) // prefill with iv + cipherdata
+	_tmp := make([]byte, 16)
+	off := 0
+	for off < len(data) {
+		b.Encrypt(_tmp, shiftRegister)
+		data[off] ^= _tmp[0]
+		shiftRegister = shiftRegister[1:]
+		off++
+	}
+	return data, nil
+}
+
+func TestCFB8MatchesLegacy(t *testing.T) {
+	key := []byte("0123Z5678K0123u567890123Z56789P1")
+	txt := make([]byte, cfb8BufferSize*3+7)
+	_, _ = rand.New(rand.NewSource(1)).Read(txt)
+
+	expected, _ := legacyEncryptCfb(bytes.Clone(txt), key)
+	encrypted, err := encryptCfb(bytes.Clone(txt), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(expected, encrypted) {
+		t.Fatal("encryption mismatch")
+	}
+
+	decrypted, err := decryptCfb(bytes.Clone(encrypted), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(txt, decrypted) {
+		t.Fatal("decryption mismatch")
+	}
+}
+
+func TestEncryptDecryptStream(t *testing.T) {
+	key := []byte("0123Z5678K0123u567890123Z56789P1")
+	txt := make([]byte, cfb8BufferSize*2+13)
+	_, _ = rand.New(rand.NewSource(2)).Read(txt)
+
+	var encrypted bytes.Buffer
+	w, err := NewEncryptWriter(&encrypted, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Write in uneven chunks so that the shift register must carry over between calls.
+	for off := 0; off < len(txt); off += 333 {
+		if _, err := w.Write(txt[off:min(off+333, len(txt))]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expected, _ := legacyEncryptCfb(bytes.Clone(txt), key)
+	if !bytes.Equal(expected, encrypted.Bytes()) {
+		t.Fatal("encryption mismatch")
+	}
+
+	r, err := NewDecryptReader(iotest.OneByteReader(&encrypted), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decrypted, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(txt, decrypted) {
+		t.Fatal("decryption mismatch")
+	}
+}
+
+func benchmarkCfb(b *testing.B, f func(data []byte, key []byte) ([]byte, error)) {
+	key := []byte("0123Z5678K0123u567890123Z56789P1")
+	data := make([]byte, 1<<20)
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := f(data, key); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkEncryptCfb(b *testing.B)       { benchmarkCfb(b, encryptCfb) }
+func BenchmarkDecryptCfb(b *testing.B)       { benchmarkCfb(b, decryptCfb) }
+func BenchmarkLegacyEncryptCfb(b *testing.B) { benchmarkCfb(b, legacyEncryptCfb) }
+func BenchmarkLegacyDecryptCfb(b *testing.B) { benchmarkCfb(b, legacyDecryptCfb) }
+
+func BenchmarkDecryptReader(b *testing.B) {
+	key := []byte("0123Z5678K0123u567890123Z56789P1")
+	data := make([]byte, 1<<20)
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r, err := NewDecryptReader(bytes.NewReader(data), key)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if _, err := io.Copy(io.Discard, r); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package pack
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	// ErrWrongKey is returned when contents.json does not decrypt to valid JSON with the key given.
+	ErrWrongKey = errors.New("wrong key: contents.json could not be decrypted")
+	// ErrNotEncrypted is returned when an operation requires an encrypted pack but the pack is not encrypted.
+	ErrNotEncrypted = errors.New("pack is not encrypted")
+	// ErrKeyRequired is returned when an operation on an encrypted pack requires the key, but none was given.
+	ErrKeyRequired = errors.New("pack is encrypted, key required")
+	// ErrInvalidHeader is returned when the unencrypted header of contents.json is malformed.
+	ErrInvalidHeader = errors.New("invalid contents.json header")
+	// ErrImplausibleContent is returned when a file does not decrypt to data matching its file extension.
+	ErrImplausibleContent = errors.New("decrypted content does not match file type")
+	// ErrUnlisted is returned by ResourcePack.Verify for files that are missing from contents.json.
+	ErrUnlisted = errors.New("file missing from contents.json")
+)
+
+// FileError is an error that occurred while processing a single file of a pack.
+type FileError struct {
+	Path string
+	Err  error
+}
+
+// Error ...
+func (e *FileError) Error() string {
+	return e.Path + ": " + e.Err.Error()
+}
+
+// Unwrap ...
+func (e *FileError) Unwrap() error {
+	return e.Err
+}
+
+// DecryptError is returned by ResourcePack.Decrypt when the pack was decrypted, but some of its files could
+// not be verified. Files holds errors for entries of contents.json that did not decrypt to plausible content,
+// and Unlisted holds files that exist in the archive but are missing from contents.json.
+type DecryptError struct {
+	Files    []*FileError
+	Unlisted []string
+}
+
+// Error ...
+func (e *DecryptError) Error() string {
+	var parts []string
+	for _, fileErr := range e.Files {
+		parts = append(parts, fileErr.Error())
+	}
+	if len(e.Unlisted) > 0 {
+		parts = append(parts, fmt.Sprintf("files missing from contents.json: %s", strings.Join(e.Unlisted, ", ")))
+	}
+	return "decrypt: " + strings.Join(parts, "; ")
+}
+
+// Unwrap ...
+func (e *DecryptError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Files))
+	for _, fileErr := range e.Files {
+		errs = append(errs, fileErr)
+	}
+	return errs
+}
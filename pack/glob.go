@@ -0,0 +1,53 @@
+package pack
+
+import (
+	"path"
+	"strings"
+)
+
+// MatchGlob reports whether the file name matches the glob pattern given. Patterns follow path.Match, with
+// the following additions:
+//   - "**" matches any number of path segments, including none.
+//   - A pattern ending with "/" matches every file in that directory, for example "texts/".
+//   - A pattern without "/" is also matched against the base name of the file, for example "*.lang".
+func MatchGlob(pattern, name string) bool {
+	pattern = strings.TrimPrefix(strings.ReplaceAll(pattern, "\\", "/"), "./")
+	if strings.HasSuffix(pattern, "/") {
+		pattern += "**"
+	}
+	if !strings.Contains(pattern, "/") && matchSegments([]string{pattern}, []string{path.Base(name)}) {
+		return true
+	}
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+// matchGlobs reports whether the file name matches any of the glob patterns given.
+func matchGlobs(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if MatchGlob(pattern, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, err := path.Match(pattern[0], name[0]); err != nil || !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
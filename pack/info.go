@@ -0,0 +1,120 @@
+package pack
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"sort"
+	"strings"
+)
+
+// Info holds information about a pack, returned by ResourcePack.Info.
+type Info struct {
+	Manifest  Manifest `json:"manifest"`
+	Encrypted bool     `json:"encrypted"`
+	// ContentsHeader is the header of the root contents.json, if the pack is encrypted.
+	ContentsHeader *ContentsHeader `json:"contents_header,omitempty"`
+	FileCount      int             `json:"file_count"`
+	TotalSize      int             `json:"total_size"`
+	// Categories holds the number and size of files per category, such as textures or models.
+	Categories []CategoryInfo `json:"categories"`
+	// BiggestFiles holds the biggest files of the pack, biggest first.
+	BiggestFiles []FileInfo `json:"biggest_files"`
+	// Hash is the hex encoded hash of the pack as computed by ResourcePack.ComputeHash.
+	Hash string `json:"hash"`
+}
+
+// ContentsHeader is the unencrypted header of a contents.json.
+type ContentsHeader struct {
+	Version uint32 `json:"version"`
+	UUID    string `json:"uuid"`
+	// Error is set if the header is invalid.
+	Error string `json:"error,omitempty"`
+}
+
+// CategoryInfo holds the number and total size of the files of a category.
+type CategoryInfo struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+	Size  int    `json:"size"`
+}
+
+// FileInfo holds the path and size of a file.
+type FileInfo struct {
+	Path string `json:"path"`
+	Size int    `json:"size"`
+}
+
+// fileCategories are the categories files are grouped into by Info, by the top-level folder they are in.
+var fileCategories = map[string]string{
+	"textures": "textures",
+	"models":   "models",
+	"sounds":   "sounds",
+	"texts":    "texts",
+	"ui":       "ui",
+}
+
+// Info returns information about the pack, including at most the number of biggest files given. The pack is
+// not modified.
+func (r *ResourcePack) Info(biggest int) (Info, error) {
+	manifest, err := r.Manifest()
+	if err != nil {
+		return Info{}, err
+	}
+	info := Info{Manifest: manifest, Encrypted: r.encrypted, Hash: hex.EncodeToString(r.ComputeHash())}
+	if contentsBytes, ok := r.files["contents.json"]; ok {
+		header := &ContentsHeader{}
+		if len(contentsBytes) >= 4 {
+			header.Version = binary.LittleEndian.Uint32(contentsBytes)
+		}
+		if header.UUID, err = parseContentsHeader(contentsBytes); err != nil {
+			header.Error = err.Error()
+		}
+		info.ContentsHeader = header
+	}
+
+	categories := map[string]*CategoryInfo{}
+	for _, fileName := range r.FileNames() {
+		if strings.HasSuffix(fileName, "/") {
+			continue
+		}
+		size := len(r.files[fileName])
+		info.FileCount++
+		info.TotalSize += size
+		info.BiggestFiles = append(info.BiggestFiles, FileInfo{Path: fileName, Size: size})
+
+		category := fileCategory(fileName)
+		if categories[category] == nil {
+			categories[category] = &CategoryInfo{Name: category}
+		}
+		categories[category].Count++
+		categories[category].Size += size
+	}
+	for _, category := range categories {
+		info.Categories = append(info.Categories, *category)
+	}
+	sort.Slice(info.Categories, func(i, j int) bool {
+		return info.Categories[i].Size > info.Categories[j].Size
+	})
+	sort.SliceStable(info.BiggestFiles, func(i, j int) bool {
+		return info.BiggestFiles[i].Size > info.BiggestFiles[j].Size
+	})
+	if len(info.BiggestFiles) > biggest {
+		info.BiggestFiles = info.BiggestFiles[:biggest]
+	}
+	return info, nil
+}
+
+// fileCategory returns the category of the file with the name given. Files of subpacks are categorised by
+// their path within the subpack.
+func fileCategory(fileName string) string {
+	if strings.HasPrefix(fileName, "subpacks/") {
+		if _, rest, ok := strings.Cut(strings.TrimPrefix(fileName, "subpacks/"), "/"); ok {
+			fileName = rest
+		}
+	}
+	dir, _, ok := strings.Cut(fileName, "/")
+	if category, found := fileCategories[dir]; ok && found {
+		return category
+	}
+	return "other"
+}
@@ -0,0 +1,157 @@
+package pack
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Manifest is the parsed manifest.json of a pack. It only holds the fields bedrockpack reads, and is not used
+// to write manifests so that unknown fields are preserved.
+type Manifest struct {
+	FormatVersion int                  `json:"format_version"`
+	Header        ManifestHeader       `json:"header"`
+	Modules       []ManifestModule     `json:"modules"`
+	Dependencies  []ManifestDependency `json:"dependencies,omitempty"`
+	Subpacks      []ManifestSubpack    `json:"subpacks,omitempty"`
+}
+
+// ManifestHeader is the header of a manifest.
+type ManifestHeader struct {
+	Name             string  `json:"name"`
+	Description      string  `json:"description"`
+	UUID             string  `json:"uuid"`
+	Version          Version `json:"version"`
+	MinEngineVersion Version `json:"min_engine_version"`
+}
+
+// ManifestModule is a module of a manifest.
+type ManifestModule struct {
+	Type        string  `json:"type"`
+	UUID        string  `json:"uuid"`
+	Description string  `json:"description,omitempty"`
+	Version     Version `json:"version"`
+}
+
+// ManifestDependency is a dependency of a manifest, either on another pack by UUID or on a script module by
+// name.
+type ManifestDependency struct {
+	UUID       string  `json:"uuid,omitempty"`
+	ModuleName string  `json:"module_name,omitempty"`
+	Version    Version `json:"version"`
+}
+
+// ManifestSubpack is a subpack declared in a manifest.
+type ManifestSubpack struct {
+	FolderName string `json:"folder_name"`
+	Name       string `json:"name"`
+	MemoryTier int    `json:"memory_tier,omitempty"`
+}
+
+// Version is a version of a pack or module. Manifests write it either as a [major, minor, patch] array or,
+// from format version 3, as a "major.minor.patch" string. A pre-release suffix of a string version is kept in
+// Suffix.
+type Version struct {
+	Major, Minor, Patch int
+	Suffix              string
+}
+
+// String returns the version formatted as "major.minor.patch".
+func (v Version) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.Suffix != "" {
+		s += "-" + v.Suffix
+	}
+	return s
+}
+
+// MarshalJSON ...
+func (v Version) MarshalJSON() ([]byte, error) {
+	return json.Marshal(v.String())
+}
+
+// UnmarshalJSON ...
+func (v *Version) UnmarshalJSON(b []byte) error {
+	var arr []int
+	if err := json.Unmarshal(b, &arr); err == nil {
+		if len(arr) > 3 {
+			return fmt.Errorf("invalid version %s", b)
+		}
+		*v = Version{}
+		for i, n := range arr {
+			*v.fields()[i] = n
+		}
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("version must be an array or a string: %s", b)
+	}
+	parsed, err := ParseVersion(s)
+	if err != nil {
+		return err
+	}
+	*v = parsed
+	return nil
+}
+
+// ParseVersion parses a version formatted as "major.minor.patch", optionally followed by "-suffix".
+func ParseVersion(s string) (Version, error) {
+	var v Version
+	s, v.Suffix, _ = strings.Cut(s, "-")
+	parts := strings.Split(s, ".")
+	if len(parts) > 3 {
+		return Version{}, fmt.Errorf("invalid version %q", s)
+	}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return Version{}, fmt.Errorf("invalid version %q", s)
+		}
+		*v.fields()[i] = n
+	}
+	return v, nil
+}
+
+// fields returns pointers to the major, minor and patch fields of the version.
+func (v *Version) fields() [3]*int {
+	return [3]*int{&v.Major, &v.Minor, &v.Patch}
+}
+
+// Manifest parses and returns the manifest of the pack.
+func (r *ResourcePack) Manifest() (Manifest, error) {
+	manifestBytes, err := r.loadFile("manifest.json")
+	if err != nil {
+		return Manifest{}, err
+	}
+	var manifest Manifest
+	if err := json.Unmarshal(manifestBytes, &manifest); err != nil {
+		return Manifest{}, fmt.Errorf("parse manifest.json: %w", err)
+	}
+	return manifest, nil
+}
+
+// Compare returns -1 if the version is lower than the version passed, 1 if it is higher and 0 if they are
+// equal. A version with a suffix is lower than the same version without one.
+func (v Version) Compare(w Version) int {
+	if c := cmp.Compare(v.Major, w.Major); c != 0 {
+		return c
+	}
+	if c := cmp.Compare(v.Minor, w.Minor); c != 0 {
+		return c
+	}
+	if c := cmp.Compare(v.Patch, w.Patch); c != 0 {
+		return c
+	}
+	switch {
+	case v.Suffix == w.Suffix:
+		return 0
+	case v.Suffix == "":
+		return 1
+	case w.Suffix == "":
+		return -1
+	}
+	return strings.Compare(v.Suffix, w.Suffix)
+}
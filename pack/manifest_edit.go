@@ -0,0 +1,209 @@
+package pack
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ManifestFields lists the header fields that may be set using SetManifestField.
+var ManifestFields = []string{"name", "description", "version", "min_engine_version"}
+
+// VersionPart is a part of a version that may be incremented using Version.Bump.
+type VersionPart string
+
+const (
+	VersionMajor VersionPart = "major"
+	VersionMinor VersionPart = "minor"
+	VersionPatch VersionPart = "patch"
+)
+
+// Bump returns the version with the part given incremented and the parts after it reset to zero. The suffix
+// of the version is dropped.
+func (v Version) Bump(part VersionPart) (Version, error) {
+	switch part {
+	case VersionMajor:
+		return Version{Major: v.Major + 1}, nil
+	case VersionMinor:
+		return Version{Major: v.Major, Minor: v.Minor + 1}, nil
+	case VersionPatch:
+		return Version{Major: v.Major, Minor: v.Minor, Patch: v.Patch + 1}, nil
+	}
+	return Version{}, fmt.Errorf("unknown version part %q, expected major, minor or patch", part)
+}
+
+// SetManifestField sets a field of the manifest header to the value given. Setting the version also sets the
+// version of every module. Versions are written in the form the manifest already uses.
+func (r *ResourcePack) SetManifestField(key, value string) error {
+	return r.updateManifest(func(manifest map[string]any) error {
+		header, ok := manifest["header"].(map[string]any)
+		if !ok {
+			return errors.New("manifest.json header not found")
+		}
+		switch key {
+		case "name", "description":
+			header[key] = value
+			return nil
+		case "version", "min_engine_version":
+			v, err := ParseVersion(value)
+			if err != nil {
+				return err
+			}
+			if key == "version" {
+				return setManifestVersion(manifest, v)
+			}
+			header[key], err = encodeVersion(manifest, header[key], v)
+			return err
+		}
+		return fmt.Errorf("unknown manifest field %q, expected one of %s", key, strings.Join(ManifestFields, ", "))
+	})
+}
+
+// BumpVersion increments the part given of the version of the pack and sets every module to the new version.
+// It returns the new version.
+func (r *ResourcePack) BumpVersion(part VersionPart) (Version, error) {
+	manifest, err := r.Manifest()
+	if err != nil {
+		return Version{}, err
+	}
+	v, err := manifest.Header.Version.Bump(part)
+	if err != nil {
+		return Version{}, err
+	}
+	return v, r.SetVersion(v)
+}
+
+// SetVersion sets the version of the pack and of every module to the version given.
+func (r *ResourcePack) SetVersion(v Version) error {
+	return r.updateManifest(func(manifest map[string]any) error {
+		return setManifestVersion(manifest, v)
+	})
+}
+
+// SetDependencyVersion sets the version of the dependencies of the pack on the pack with the UUID given. It
+// returns false if the pack does not depend on it, in which case the manifest is left unchanged.
+func (r *ResourcePack) SetDependencyVersion(uuid string, v Version) (bool, error) {
+	found := false
+	err := r.updateManifest(func(manifest map[string]any) error {
+		dependencies, _ := manifest["dependencies"].([]any)
+		for _, d := range dependencies {
+			dependency, ok := d.(map[string]any)
+			if !ok || dependency["uuid"] != uuid {
+				continue
+			}
+			encoded, err := encodeVersion(manifest, dependency["version"], v)
+			if err != nil {
+				return err
+			}
+			dependency["version"] = encoded
+			found = true
+		}
+		if !found {
+			return errUnchanged
+		}
+		return nil
+	})
+	return found, err
+}
+
+// errUnchanged is returned by the function passed to updateManifest to leave the manifest as it is.
+var errUnchanged = errors.New("manifest unchanged")
+
+// setManifestVersion sets the header version and the version of every module of the manifest passed.
+func setManifestVersion(manifest map[string]any, v Version) error {
+	header, ok := manifest["header"].(map[string]any)
+	if !ok {
+		return errors.New("manifest.json header not found")
+	}
+	encoded, err := encodeVersion(manifest, header["version"], v)
+	if err != nil {
+		return err
+	}
+	header["version"] = encoded
+
+	modules, _ := manifest["modules"].([]any)
+	for _, m := range modules {
+		module, ok := m.(map[string]any)
+		if !ok {
+			return errors.New("manifest.json module is not an object")
+		}
+		if module["version"], err = encodeVersion(manifest, module["version"], v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// encodeVersion returns the version passed in the form of the current value of the field it replaces: an
+// array or a string. If the field is not set yet, the string form is used from format version 3.
+func encodeVersion(manifest map[string]any, current any, v Version) (any, error) {
+	useString := false
+	switch current.(type) {
+	case string:
+		useString = true
+	case nil:
+		n, _ := manifest["format_version"].(json.Number)
+		formatVersion, _ := n.Int64()
+		useString = formatVersion >= 3
+	}
+	if useString {
+		return v.String(), nil
+	}
+	if v.Suffix != "" {
+		return nil, fmt.Errorf("version %s has a suffix, which cannot be written as an array", v)
+	}
+	return []int{v.Major, v.Minor, v.Patch}, nil
+}
+
+// updateManifest decodes the manifest of the pack, passes it to the function given and writes it back. The
+// manifest is indented if it was before. If the function returns errUnchanged, the manifest is not written.
+func (r *ResourcePack) updateManifest(f func(manifest map[string]any) error) error {
+	manifestBytes, err := r.loadFile("manifest.json")
+	if err != nil {
+		return err
+	}
+	manifest, err := r.manifestMap()
+	if err != nil {
+		return err
+	}
+	if err := f(manifest); err != nil {
+		if errors.Is(err, errUnchanged) {
+			return nil
+		}
+		return err
+	}
+	return r.writeManifestMap(manifest, bytes.Contains(manifestBytes, []byte("\n")))
+}
+
+// manifestMap returns the manifest of the pack decoded as a map, so that it can be modified without losing
+// unknown fields.
+func (r *ResourcePack) manifestMap() (map[string]any, error) {
+	manifestBytes, err := r.loadFile("manifest.json")
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(manifestBytes))
+	dec.UseNumber()
+	var manifest map[string]any
+	if err := dec.Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("parse manifest.json: %w", err)
+	}
+	return manifest, nil
+}
+
+// writeManifestMap encodes the manifest passed and writes it to manifest.json, indented if requested.
+func (r *ResourcePack) writeManifestMap(manifest map[string]any, indent bool) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if indent {
+		enc.SetIndent("", "  ")
+	}
+	if err := enc.Encode(manifest); err != nil {
+		return err
+	}
+	r.files["manifest.json"] = bytes.TrimSuffix(buf.Bytes(), []byte("\n"))
+	return nil
+}
@@ -0,0 +1,120 @@
+package pack
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestVersionUnmarshal(t *testing.T) {
+	tests := map[string]Version{
+		`[1,2,3]`:        {Major: 1, Minor: 2, Patch: 3},
+		`[1,21]`:         {Major: 1, Minor: 21},
+		`"1.21.0"`:       {Major: 1, Minor: 21},
+		`"2.0.1-beta.1"`: {Major: 2, Patch: 1, Suffix: "beta.1"},
+	}
+	for input, expected := range tests {
+		var v Version
+		if err := json.Unmarshal([]byte(input), &v); err != nil {
+			t.Fatalf("%s: %v", input, err)
+		}
+		if v != expected {
+			t.Errorf("%s: got %+v, want %+v", input, v, expected)
+		}
+	}
+	var v Version
+	if err := json.Unmarshal([]byte(`"1.x.0"`), &v); err == nil {
+		t.Error("expected error for invalid version")
+	}
+}
+
+func TestInfo(t *testing.T) {
+	rp := newTestPack(t, testPackFiles())
+	info, err := rp.Info(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Manifest.Header.Name != "test" || info.Manifest.Header.MinEngineVersion != (Version{Major: 1, Minor: 20}) {
+		t.Fatalf("unexpected manifest header: %+v", info.Manifest.Header)
+	}
+	if info.FileCount != 4 || len(info.BiggestFiles) != 1 || info.BiggestFiles[0].Path != "manifest.json" {
+		t.Fatalf("unexpected files: %d, %v", info.FileCount, info.BiggestFiles)
+	}
+	counts := map[string]int{}
+	for _, category := range info.Categories {
+		counts[category.Name] = category.Count
+	}
+	if counts["textures"] != 1 || counts["models"] != 1 || counts["texts"] != 1 || counts["other"] != 1 {
+		t.Fatalf("unexpected categories: %v", info.Categories)
+	}
+}
+
+func TestNewAddon(t *testing.T) {
+	rp, bp, err := NewAddon(NewConfig{Name: "Test", MinEngineVersion: Version{Major: 1, Minor: 21}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		pack, dependency *ResourcePack
+		module           string
+	}{{rp, bp, "resources"}, {bp, rp, "data"}} {
+		m, err := test.pack.Manifest()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m.Header.Name != "Test" || m.Header.UUID != test.pack.UUID() || m.Header.MinEngineVersion != (Version{Major: 1, Minor: 21}) {
+			t.Errorf("unexpected header %+v", m.Header)
+		}
+		if len(m.Modules) != 1 || m.Modules[0].Type != test.module || m.Modules[0].UUID == m.Header.UUID {
+			t.Errorf("unexpected modules %+v", m.Modules)
+		}
+		if len(m.Dependencies) != 1 || m.Dependencies[0].UUID != test.dependency.UUID() {
+			t.Errorf("unexpected dependencies %+v", m.Dependencies)
+		}
+	}
+}
+
+func TestBumpVersion(t *testing.T) {
+	rp := newTestPack(t, map[string][]byte{
+		"manifest.json": []byte(`{"format_version":3,"header":{"name":"test","uuid":"2b2f4a1e-7d0c-4a8a-9f7e-3c1e5f0d9a11","version":"1.2.3","min_engine_version":[1,21,0]},` +
+			`"modules":[{"type":"resources","uuid":"9c3e2d1f-0a4b-4c5d-8e6f-7a8b9c0d1e2f","version":"1.2.3"}],` +
+			`"dependencies":[{"uuid":"b7a1c3d5-e7f9-4b1d-a3c5-e7f9b1d3a5c7","version":[1,0,0]}]}`),
+	})
+	v, err := rp.BumpVersion(VersionMinor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != (Version{Major: 1, Minor: 3}) {
+		t.Fatalf("got %v, want 1.3.0", v)
+	}
+	if ok, err := rp.SetDependencyVersion("b7a1c3d5-e7f9-4b1d-a3c5-e7f9b1d3a5c7", Version{Major: 2}); !ok || err != nil {
+		t.Fatalf("SetDependencyVersion: %v, %v", ok, err)
+	}
+	if err := rp.SetManifestField("min_engine_version", "1.21.50"); err != nil {
+		t.Fatal(err)
+	}
+
+	manifest, _ := rp.loadFile("manifest.json")
+	for _, expected := range []string{`"version":"1.3.0"`, `"min_engine_version":[1,21,50]`, `"version":[2,0,0]`} {
+		if !bytes.Contains(manifest, []byte(expected)) {
+			t.Errorf("manifest does not contain %s: %s", expected, manifest)
+		}
+	}
+	if bytes.Count(manifest, []byte(`"version":"1.3.0"`)) != 2 {
+		t.Errorf("module version not bumped: %s", manifest)
+	}
+	if _, err := (Version{Major: 1, Suffix: "beta"}).Bump("huge"); err == nil {
+		t.Error("expected error for unknown version part")
+	}
+
+	// A manifest without a format version gets the array form of a version field it does not have yet.
+	rp = newTestPack(t, map[string][]byte{
+		"manifest.json": []byte(`{"header":{"name":"test","uuid":"2b2f4a1e-7d0c-4a8a-9f7e-3c1e5f0d9a11","version":[1,0,0]}}`),
+	})
+	if err := rp.SetManifestField("min_engine_version", "1.21.0"); err != nil {
+		t.Fatal(err)
+	}
+	if manifest, _ := rp.loadFile("manifest.json"); !bytes.Contains(manifest, []byte(`"min_engine_version":[1,21,0]`)) {
+		t.Errorf("min_engine_version not set: %s", manifest)
+	}
+}
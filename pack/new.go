@@ -0,0 +1,135 @@
+package pack
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/google/uuid"
+	"image"
+	"image/color"
+	"image/png"
+)
+
+// PackType is the type of pack created by New.
+type PackType string
+
+const (
+	PackTypeResource PackType = "resource"
+	PackTypeBehavior PackType = "behavior"
+)
+
+// NewConfig holds the settings of a pack created by New.
+type NewConfig struct {
+	Type             PackType
+	Name             string
+	Description      string
+	MinEngineVersion Version
+}
+
+// New creates a pack with a valid manifest with fresh UUIDs, a placeholder pack_icon.png and the standard
+// folders and language files of its type.
+func New(conf NewConfig) (*ResourcePack, error) {
+	moduleType, ok := map[PackType]string{PackTypeResource: "resources", PackTypeBehavior: "data"}[conf.Type]
+	if !ok {
+		return nil, fmt.Errorf("unknown pack type %q", conf.Type)
+	}
+
+	version := []int{1, 0, 0}
+	manifest := map[string]any{
+		"format_version": 2,
+		"header": map[string]any{
+			"name":               conf.Name,
+			"description":        conf.Description,
+			"uuid":               uuid.NewString(),
+			"version":            version,
+			"min_engine_version": []int{conf.MinEngineVersion.Major, conf.MinEngineVersion.Minor, conf.MinEngineVersion.Patch},
+		},
+		"modules": []map[string]any{{
+			"type":    moduleType,
+			"uuid":    uuid.NewString(),
+			"version": version,
+		}},
+	}
+	manifestBytes, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	icon, err := placeholderIcon(conf.Type)
+	if err != nil {
+		return nil, err
+	}
+
+	files := map[string][]byte{
+		"manifest.json":        manifestBytes,
+		"pack_icon.png":        icon,
+		"texts/en_US.lang":     []byte(fmt.Sprintf("pack.name=%s\npack.description=%s\n", conf.Name, conf.Description)),
+		"texts/languages.json": []byte("[\n  \"en_US\"\n]"),
+	}
+	switch conf.Type {
+	case PackTypeResource:
+		files["textures/"] = nil
+	case PackTypeBehavior:
+		files["entities/"] = nil
+		files["items/"] = nil
+	}
+
+	rp := &ResourcePack{}
+	if err := rp.loadFiles(files); err != nil {
+		return nil, err
+	}
+	return rp, nil
+}
+
+// NewAddon creates a resource pack and a behavior pack with the settings given, that list each other as
+// dependencies. The Type of the config passed is ignored.
+func NewAddon(conf NewConfig) (resourcePack, behaviorPack *ResourcePack, err error) {
+	conf.Type = PackTypeResource
+	if resourcePack, err = New(conf); err != nil {
+		return nil, nil, err
+	}
+	conf.Type = PackTypeBehavior
+	if behaviorPack, err = New(conf); err != nil {
+		return nil, nil, err
+	}
+	if err := resourcePack.addDependency(behaviorPack); err != nil {
+		return nil, nil, err
+	}
+	if err := behaviorPack.addDependency(resourcePack); err != nil {
+		return nil, nil, err
+	}
+	return resourcePack, behaviorPack, nil
+}
+
+// addDependency adds the pack passed to the dependencies in the manifest of the pack.
+func (r *ResourcePack) addDependency(dependency *ResourcePack) error {
+	dependencyManifest, err := dependency.Manifest()
+	if err != nil {
+		return err
+	}
+	v := dependencyManifest.Header.Version
+	return r.updateManifest(func(manifest map[string]any) error {
+		dependencies, _ := manifest["dependencies"].([]any)
+		manifest["dependencies"] = append(dependencies, map[string]any{
+			"uuid":    dependency.uuid,
+			"version": []int{v.Major, v.Minor, v.Patch},
+		})
+		return nil
+	})
+}
+
+// placeholderIcon returns a plain 64x64 PNG used as pack_icon.png of new packs.
+func placeholderIcon(t PackType) ([]byte, error) {
+	c := color.RGBA{R: 0x3c, G: 0x8d, B: 0xe0, A: 0xff}
+	if t == PackTypeBehavior {
+		c = color.RGBA{R: 0x5a, G: 0xb5, B: 0x4b, A: 0xff}
+	}
+	img := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	for i := 0; i < len(img.Pix); i += 4 {
+		img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = c.R, c.G, c.B, c.A
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
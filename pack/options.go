@@ -0,0 +1,35 @@
+package pack
+
+import "bytes"
+
+// EncryptOptions holds options that control how a pack is encrypted by ResourcePack.EncryptWithOptions.
+type EncryptOptions struct {
+	// Include holds glob patterns of files that should be encrypted. If empty, every file is encrypted.
+	Include []string
+	// Exclude holds glob patterns of files that should be left unencrypted. Exclude takes precedence over
+	// Include. Excluded files are still listed in contents.json, but without a key.
+	Exclude []string
+	// Seed, if set, is used to derive the key of every file from the seed and the path of the file, so that
+	// encrypting equal packs with the same seed produces identical output. If nil, every file gets a random key.
+	Seed []byte
+}
+
+// fileKey returns the key to encrypt the file with the name given with.
+func (opts EncryptOptions) fileKey(fileName string) []byte {
+	if opts.Seed == nil {
+		return GenerateKey()
+	}
+	return GenerateKeyFromSeed(sha256(append(append(bytes.Clone(opts.Seed), 0), fileName...)))
+}
+
+// encrypts reports whether the file with the name given should be encrypted.
+func (opts EncryptOptions) encrypts(fileName string) bool {
+	// The client needs to read these files before it has the key, so they are never encrypted.
+	if fileName == "manifest.json" || fileName == "pack_icon.png" {
+		return false
+	}
+	if len(opts.Include) > 0 && !matchGlobs(opts.Include, fileName) {
+		return false
+	}
+	return !matchGlobs(opts.Exclude, fileName)
+}
@@ -2,98 +2,333 @@ package pack
 
 import (
 	"bytes"
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
 	"github.com/sandertv/gophertunnel/minecraft"
 	"github.com/sandertv/gophertunnel/minecraft/resource"
-	"io"
+	"io/fs"
 	"log/slog"
+	"math/rand/v2"
+	"net"
 	"net/http"
+	"slices"
+	"sync"
 	"time"
 )
 
 type OTF struct {
-	log      *slog.Logger
-	listener *minecraft.Listener
-	orgName  string
-	repoName string
-	branch   string
-	// pat is personal access token
-	pat               string
-	currentPackCommit string
-	currentPackKey    string
-	currentPack       *resource.Pack
+	log    *slog.Logger
+	source PackSource
+
+	// mu guards the fields below, which are written by the poll loop and read by the users of the OTF.
+	mu                  sync.Mutex
+	listener            *minecraft.Listener
+	packListener        packListener
+	currentPackRevision string
+	currentPackKey      string
+	currentPack         *resource.Pack
+	// started is set by the first call to Start.
+	started bool
+
+	pollInterval time.Duration
+	clock        clock
+	cacheDir     string
+
+	packHost       PackHost
+	packAddr       string
+	packServer     *http.Server
+	packURLMinSize int
+	httpClient     *http.Client
+	// hostedPacks holds the names of the packs on the pack host, oldest first. It is only used by the poll loop.
+	hostedPacks []string
+
+	webhookSecret []byte
+	webhookAddr   string
+	webhookServer *http.Server
+	// trigger is sent to by the webhook handler to check for a new revision immediately.
+	trigger chan struct{}
+
+	stopOnce sync.Once
+	stop     chan struct{}
+	// done is closed once the poll loop exits, or once Start fails.
+	done chan struct{}
+}
+
+// packListener is the part of minecraft.Listener that OTF uses to serve the pack.
+type packListener interface {
+	AddResourcePack(pack *resource.Pack)
+	RemoveResourcePack(uuid string)
 }
 
 const (
 	otfUserAgent = "BedrockPack-OTF-Agent"
+	// otfPollInterval is the default interval at which OTF checks for updates of the pack.
+	otfPollInterval = 10 * time.Minute
+	// otfWebhookPollInterval is the default poll interval if webhooks are enabled, in which case polling is only
+	// a fallback for missed deliveries.
+	otfWebhookPollInterval = time.Hour
+	// otfRetryBaseDelay is the delay before the first retry after a transient failure. It doubles with every
+	// consecutive failure, up to the poll interval.
+	otfRetryBaseDelay = 10 * time.Second
 )
 
 type OTFConfig struct {
+	// Source is the source of the pack. If nil, the pack is built from the GitHub repository set by OrgName,
+	// RepoName, Branch and PAT.
+	Source PackSource
+
 	OrgName  string
 	RepoName string
 	Branch   string
 	PAT      string
+	// APIURL is the URL of the GitHub API, for GitHub Enterprise Server. It defaults to https://api.github.com.
+	APIURL string
+	// App, if set, authenticates as an installation of a GitHub App instead of using PAT.
+	App *GitHubApp
+	// Subdir, TagPattern and ReleaseAsset are passed to the GitHubSource. See GitHubSource for details.
+	Subdir       string
+	TagPattern   string
+	ReleaseAsset string
+	// HTTPClient is the HTTP client of the GitHubSource and of the requests of OTF itself, which may set a custom
+	// timeout or transport. It defaults to a client with a timeout of 2 minutes.
+	HTTPClient *http.Client
+	// PollInterval is the interval at which the source is checked for a new revision. It defaults to 10
+	// minutes, or an hour if WebhookSecret is set.
+	PollInterval time.Duration
+
+	// WebhookSecret enables the webhook handler returned by OTF.WebhookHandler. Push and release events of GitHub
+	// and Gitea signed with the secret trigger an immediate check for a new revision.
+	WebhookSecret string
+	// WebhookAddr is an optional address, such as ":8080", on which OTF serves the webhook handler while it is
+	// running. It requires WebhookSecret.
+	WebhookAddr string
+
+	// CacheDir is an optional directory in which the compiled pack, its key and its revision are stored. On
+	// start, the cached pack is served immediately and the source is checked for a new revision in the
+	// background, so that the pack is available even if the source is not.
+	CacheDir string
+
+	// PackHost, if set, hosts packs of at least PackURLMinSize bytes, which clients then download over HTTP
+	// instead of receiving them in chunks from the server. If hosting a pack fails, the server sends it instead.
+	PackHost PackHost
+	// PackURLMinSize is the minimum size of a pack to be downloaded from the PackHost. Smaller packs are sent by
+	// the server, which is as fast for small packs.
+	PackURLMinSize int
+	// PackAddr is an optional address, such as ":8081", on which OTF serves PackHost while it is running. It
+	// requires a PackHost that implements http.Handler, such as MemoryPackHost.
+	PackAddr string
 }
 
 func (conf OTFConfig) New(log *slog.Logger) *OTF {
+	if conf.Source == nil {
+		conf.Source = &GitHubSource{
+			APIURL: conf.APIURL,
+			Owner:  conf.OrgName,
+			Repo:   conf.RepoName,
+			Branch: conf.Branch,
+			Subdir: conf.Subdir,
+			Token:  conf.PAT,
+			App:    conf.App,
+			Client: conf.HTTPClient,
+
+			TagPattern:   conf.TagPattern,
+			ReleaseAsset: conf.ReleaseAsset,
+		}
+	}
+	if conf.PollInterval <= 0 {
+		conf.PollInterval = otfPollInterval
+		if conf.WebhookSecret != "" {
+			conf.PollInterval = otfWebhookPollInterval
+		}
+	}
 	return &OTF{
-		log:      log.With("pack_repo", conf.OrgName+"/"+conf.RepoName+":"+conf.Branch),
-		orgName:  conf.OrgName,
-		repoName: conf.RepoName,
-		branch:   conf.Branch,
-		pat:      conf.PAT,
+		log:            log.With("pack_source", fmt.Sprint(conf.Source)),
+		source:         conf.Source,
+		pollInterval:   conf.PollInterval,
+		clock:          realClock{},
+		cacheDir:       conf.CacheDir,
+		packHost:       conf.PackHost,
+		packAddr:       conf.PackAddr,
+		packURLMinSize: conf.PackURLMinSize,
+		httpClient:     conf.HTTPClient,
+		webhookSecret:  []byte(conf.WebhookSecret),
+		webhookAddr:    conf.WebhookAddr,
+		trigger:        make(chan struct{}, 1),
+		stop:           make(chan struct{}),
+		done:           make(chan struct{}),
 	}
 }
 
-// Start ...
-func (o *OTF) Start() error {
-	// try first tick
-	if err := o.tick(); err != nil {
+// Start builds the pack from the current revision of the source and returns an error if that fails, unless a
+// pack is cached in the cache directory, which is served instead while the source is checked in the background.
+// It then checks for new revisions in the background every poll interval, or when a webhook is received, until
+// the context passed is cancelled or Stop is called. Start may only be called once.
+func (o *OTF) Start(ctx context.Context) error {
+	o.mu.Lock()
+	started := o.started
+	o.started = true
+	o.mu.Unlock()
+	if started {
+		return errors.New("otf already started")
+	}
+	if err := o.start(ctx); err != nil {
+		// A concurrent call to Stop waits for done.
+		close(o.done)
 		return err
 	}
+	return nil
+}
 
-	// then start the ticker
-	ticker := time.NewTicker(10 * time.Minute)
-	defer ticker.Stop()
-	go func() {
-		for {
-			select {
-			case <-ticker.C:
-				if err := o.tick(); err != nil {
-					o.log.Error("failed to tick", "error", err)
-				}
+// start starts the servers of the OTF, builds or loads the first pack and runs the poll loop.
+func (o *OTF) start(ctx context.Context) error {
+	var webhookListener net.Listener
+	if o.webhookAddr != "" {
+		if len(o.webhookSecret) == 0 {
+			return errors.New("webhook address set without webhook secret")
+		}
+		var err error
+		if webhookListener, err = net.Listen("tcp", o.webhookAddr); err != nil {
+			return fmt.Errorf("listen for webhooks: %w", err)
+		}
+	}
+	if o.packAddr != "" {
+		// Packs are served before the first build, which downloads the pack from its URL to verify it.
+		if err := o.servePacks(); err != nil {
+			if webhookListener != nil {
+				_ = webhookListener.Close()
 			}
+			return err
 		}
-	}()
+	}
+	if o.loadCachedPack(ctx) {
+		// The cached pack may be outdated, which the poll loop checks right away.
+		o.trigger <- struct{}{}
+	} else if err := o.tick(ctx); err != nil {
+		if webhookListener != nil {
+			_ = webhookListener.Close()
+		}
+		if o.packServer != nil {
+			_ = o.packServer.Close()
+		}
+		return err
+	}
+
+	if webhookListener != nil {
+		o.webhookServer = &http.Server{Handler: o.WebhookHandler(), ReadHeaderTimeout: 10 * time.Second}
+		go o.serve(o.webhookServer, webhookListener, "webhooks")
+	}
+	go o.run(ctx)
 	return nil
 }
 
-// tick ...
-func (o *OTF) tick() error {
-	commitHash, err := o.lastCommit(o.branch)
+// Stop stops checking for updates of the pack and serving webhooks. It waits for a build that is in progress
+// to finish. The pack remains on the listener, but is no longer served on the pack address if one is set.
+func (o *OTF) Stop() {
+	o.stopOnce.Do(func() {
+		close(o.stop)
+	})
+	o.mu.Lock()
+	started := o.started
+	o.mu.Unlock()
+	if started {
+		<-o.done
+	}
+}
+
+// servePacks starts serving the pack host on the pack address.
+func (o *OTF) servePacks() error {
+	handler, ok := o.packHost.(http.Handler)
+	if !ok {
+		return errors.New("pack address set without a pack host that serves packs")
+	}
+	l, err := net.Listen("tcp", o.packAddr)
 	if err != nil {
-		return fmt.Errorf("failed to get last commit: %w", err)
+		return fmt.Errorf("listen for pack downloads: %w", err)
 	}
+	o.packServer = &http.Server{Handler: handler, ReadHeaderTimeout: 10 * time.Second}
+	go o.serve(o.packServer, l, "packs")
+	return nil
+}
 
-	if o.currentPackCommit == commitHash {
-		return nil
+// serve serves HTTP requests on the listener passed until the server is closed.
+func (o *OTF) serve(server *http.Server, l net.Listener, name string) {
+	if err := server.Serve(l); !errors.Is(err, http.ErrServerClosed) {
+		o.log.Error("failed to serve "+name, "error", err)
+	}
+}
+
+// run checks for updates every poll interval or when triggered by a webhook, until the context is cancelled
+// or the OTF is stopped. Failed checks are retried sooner or later than the poll interval, as returned by
+// retryDelay.
+func (o *OTF) run(ctx context.Context) {
+	defer close(o.done)
+	if o.webhookServer != nil {
+		defer o.webhookServer.Close()
+	}
+	if o.packServer != nil {
+		defer o.packServer.Close()
 	}
+	wait, failures := o.pollInterval, 0
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-o.stop:
+			return
+		case <-o.trigger:
+		case <-o.clock.After(wait):
+		}
+		wait = o.pollInterval
+		if err := o.tick(ctx); err != nil {
+			failures++
+			wait = o.retryDelay(err, failures)
+			o.log.Error("failed to tick", "error", err, "retry_in", wait)
+			continue
+		}
+		failures = 0
+	}
+}
 
-	if o.currentPackCommit != "" {
-		o.log.Info("detected pack update, updating pack", "new_commit_hash", commitHash)
+// retryDelay returns how long to wait before checking for updates again after the error passed, which is the
+// consecutive failure given. A server that asked to wait, such as when the rate limit of GitHub is used up, is
+// waited for. Transient failures are retried with exponential backoff up to the poll interval, and other
+// failures after the poll interval.
+func (o *OTF) retryDelay(err error, failures int) time.Duration {
+	var statusErr *httpStatusError
+	if errors.As(err, &statusErr) && statusErr.RetryAfter > 0 {
+		return statusErr.RetryAfter
+	}
+	if !transientError(err) {
+		return o.pollInterval
 	}
+	d := min(otfRetryBaseDelay<<min(failures-1, 16), o.pollInterval)
+	// The jitter spreads out the retries of servers that failed at the same time, such as during an outage.
+	return d/2 + rand.N(d/2+1)
+}
 
-	o.log.Info("downloading pack")
-	packBytes, err := o.downloadRepoZip(commitHash)
+// tick checks the source for a new revision, and builds the pack and swaps it on the listener if there is one.
+func (o *OTF) tick(ctx context.Context) error {
+	revision, err := o.source.Revision(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to download pack: %w", err)
+		return fmt.Errorf("failed to get revision: %w", err)
 	}
 
-	o.log.Info("loading pack")
-	pack, err := LoadResourcePackFromBytes(packBytes)
+	o.mu.Lock()
+	currentPackRevision := o.currentPackRevision
+	o.mu.Unlock()
+	if currentPackRevision == revision {
+		return nil
+	}
+
+	if currentPackRevision != "" {
+		o.log.Info("detected pack update, updating pack", "new_revision", revision)
+	}
+
+	o.log.Info("fetching pack")
+	pack, err := o.source.Fetch(ctx, revision)
 	if err != nil {
-		return fmt.Errorf("failed to load pack: %w", err)
+		return fmt.Errorf("failed to fetch pack: %w", err)
 	}
 
 	pack.DeleteFile("README.md")
@@ -118,7 +353,7 @@ func (o *OTF) tick() error {
 	}
 
 	o.log.Info("encrypting pack", "pack_key", string(packKey))
-	if err := pack.Encrypt(packKey); err != nil {
+	if err := pack.EncryptWithOptions(packKey, EncryptOptions{Seed: packHash}); err != nil {
 		return fmt.Errorf("failed to encrypt pack: %w", err)
 	}
 
@@ -128,122 +363,145 @@ func (o *OTF) tick() error {
 		return fmt.Errorf("failed to save pack: %w", err)
 	}
 
-	packBytes = nil // free memory
-
 	o.log.Info("compiling pack")
 	compiledPack, err := resource.Read(bytes.NewBuffer(compiledPackBytes))
 	if err != nil {
 		return fmt.Errorf("failed to read pack: %w", err)
 	}
 
-	compiledPackBytes = nil // free memory
-
-	o.log.Info("pack updated", "pack_uuid", compiledPack.UUID().String())
-	var prevPackUUID string
-	if o.currentPack != nil {
-		prevPackUUID = o.currentPack.UUID().String()
-	}
-	o.currentPackKey = string(packKey)
-	o.currentPackCommit = commitHash
-	o.currentPack = compiledPack
-
-	if o.listener != nil {
-		if prevPackUUID != "" {
-			o.listener.RemoveResourcePack(prevPackUUID)
+	if o.cacheDir != "" {
+		if err := o.saveCache(compiledPackBytes, string(packKey), revision); err != nil {
+			o.log.Warn("failed to cache pack", "error", err)
 		}
-		o.addPackToListener()
 	}
+	compiledPack = o.hostPack(ctx, compiledPack, compiledPackBytes)
 
-	return nil
-}
+	compiledPackBytes = nil // free memory
 
-// SetListener ...
-func (o *OTF) SetListener(listener *minecraft.Listener) {
-	o.listener = listener
-	o.addPackToListener()
+	o.log.Info("pack updated", "pack_uuid", compiledPack.UUID().String())
+	o.swapPack(compiledPack, string(packKey), revision)
+	return nil
 }
 
-// addPackToListener adds the pack to the listener.
-func (o *OTF) addPackToListener() {
-	if o.listener == nil || o.currentPack == nil {
-		return
+// hostPack puts the compiled pack passed on the pack host if it has at least the minimum size, and returns the
+// pack with the download URL of the host. Otherwise, or if hosting fails, it returns the pack passed, which the
+// server then sends to clients itself.
+func (o *OTF) hostPack(ctx context.Context, pack *resource.Pack, data []byte) *resource.Pack {
+	if o.packHost == nil || len(data) < o.packURLMinSize {
+		return pack
 	}
-	o.listener.AddResourcePack(o.currentPack.WithContentKey(o.currentPackKey))
-}
-
-// Listener ...
-func (o *OTF) Listener() *minecraft.Listener {
-	return o.listener
-}
+	name := pack.UUID().String() + ".zip"
+	hosted, u, err := o.putPack(ctx, name, pack.UUID().String(), data)
+	if err != nil {
+		o.log.Warn("failed to host pack, sending it from the server instead", "error", err)
+		return pack
+	}
+	o.log.Info("hosting pack", "url", u)
 
-// initializeHeaders sets the necessary headers for the HTTP request.
-func (o *OTF) initializeHeaders(req *http.Request) {
-	if o.pat != "" {
-		req.Header.Set("Authorization", "Bearer "+o.pat)
+	o.hostedPacks = append(slices.DeleteFunc(o.hostedPacks, func(n string) bool { return n == name }), name)
+	// The previous pack is kept, as clients that joined before the swap may still be downloading it.
+	for len(o.hostedPacks) > 2 {
+		if err := o.packHost.Delete(ctx, o.hostedPacks[0]); err != nil {
+			o.log.Warn("failed to delete hosted pack", "name", o.hostedPacks[0], "error", err)
+		}
+		o.hostedPacks = o.hostedPacks[1:]
 	}
-	req.Header.Set("User-Agent", otfUserAgent)
-	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	return hosted
 }
 
-// lastCommit fetches the latest commit hash from the given branch.
-func (o *OTF) lastCommit(branch string) (string, error) {
-	url := fmt.Sprintf("https://api.github.com/repos/%s/%s/commits?sha=%s&per_page=1", o.orgName, o.repoName, branch)
-
-	req, err := http.NewRequest("GET", url, nil)
+// putPack puts the compiled pack passed on the pack host under the name given, nested in the directory passed,
+// and returns the pack read from the nested archive with the download URL of the host.
+func (o *OTF) putPack(ctx context.Context, name, dir string, data []byte) (*resource.Pack, string, error) {
+	nested, err := nestPackArchive(data, dir)
 	if err != nil {
-		return "", err
+		return nil, "", err
 	}
-	o.initializeHeaders(req)
-
-	resp, err := http.DefaultClient.Do(req)
+	u, err := o.packHost.Put(ctx, name, nested)
 	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("GitHub API returned status: %d", resp.StatusCode)
+		return nil, "", err
 	}
-
-	var commits []struct {
-		SHA string `json:"sha"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&commits); err != nil {
-		return "", err
+	// The pack is read back from the URL to verify that clients can download it, which also sets the download
+	// URL of the pack read.
+	hosted, err := readPackURL(ctx, o.httpClient, u)
+	if err != nil {
+		return nil, "", fmt.Errorf("read hosted pack: %w", err)
 	}
-
-	if len(commits) == 0 {
-		return "", fmt.Errorf("no commits found for branch: %s", branch)
+	if checksum := hosted.Checksum(); !bytes.Equal(checksum[:], sha256(nested)) {
+		return nil, "", fmt.Errorf("hosted pack at %s does not match the pack uploaded", u)
 	}
-
-	return commits[0].SHA, nil
+	return hosted, u, nil
 }
 
-// downloadRepoZip downloads the entire repository as a .zip for a specific commit or branch.
-func (o *OTF) downloadRepoZip(ref string) ([]byte, error) {
-	url := fmt.Sprintf("https://api.github.com/repos/%s/%s/zipball/%s", o.orgName, o.repoName, ref)
-
-	req, err := http.NewRequest("GET", url, nil)
+// loadCachedPack makes the pack in the cache directory the current pack, if there is one. It reports whether a
+// pack was loaded.
+func (o *OTF) loadCachedPack(ctx context.Context) bool {
+	if o.cacheDir == "" {
+		return false
+	}
+	pack, data, meta, err := o.loadCache()
 	if err != nil {
-		return nil, err
+		if !errors.Is(err, fs.ErrNotExist) {
+			o.log.Warn("failed to load cached pack", "error", err)
+		}
+		return false
 	}
-	o.initializeHeaders(req)
+	o.log.Info("serving cached pack", "revision", meta.Revision, "pack_uuid", pack.UUID().String())
+	o.swapPack(o.hostPack(ctx, pack, data), meta.Key, meta.Revision)
+	return true
+}
 
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return nil, err
+// swapPack makes the pack passed the current pack and replaces the previous pack on the listener with it. The
+// new pack is added before the previous pack is removed, so that the listener always has a pack to send to
+// players that join in between.
+func (o *OTF) swapPack(pack *resource.Pack, key, revision string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	prev := o.currentPack
+	o.currentPack, o.currentPackKey, o.currentPackRevision = pack, key, revision
+	if o.packListener == nil {
+		return
+	}
+	// A revision that does not change the content of the pack results in the same UUID, in which case the pack
+	// on the listener is left as it is: removing the previous pack by its UUID would remove the new pack too.
+	if prev != nil && prev.UUID() == pack.UUID() {
+		return
+	}
+	o.packListener.AddResourcePack(pack.WithContentKey(key))
+	if prev != nil {
+		o.packListener.RemoveResourcePack(prev.UUID().String())
 	}
-	defer resp.Body.Close()
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to download repository: %s (status: %d)", url, resp.StatusCode)
+// SetListener sets the listener to serve the pack on, and adds the current pack to it.
+func (o *OTF) SetListener(listener *minecraft.Listener) {
+	// A nil listener must not be stored as a non-nil interface.
+	var l packListener
+	if listener != nil {
+		l = listener
 	}
+	o.setPackListener(listener, l)
+}
 
-	// Read the response body into a byte slice
-	data, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
+// setPackListener sets the listener returned by Listener and the listener that the pack is added to, which
+// differ if the OTF is managed by an OTFManager.
+func (o *OTF) setPackListener(listener *minecraft.Listener, l packListener) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.listener, o.packListener = listener, l
+	o.addPackToListener()
+}
+
+// addPackToListener adds the pack to the listener. It must be called with o.mu held.
+func (o *OTF) addPackToListener() {
+	if o.packListener == nil || o.currentPack == nil {
+		return
 	}
+	o.packListener.AddResourcePack(o.currentPack.WithContentKey(o.currentPackKey))
+}
 
-	return data, nil
+// Listener ...
+func (o *OTF) Listener() *minecraft.Listener {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.listener
 }
@@ -0,0 +1,98 @@
+package pack
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/sandertv/gophertunnel/minecraft/resource"
+	"os"
+	"path/filepath"
+)
+
+// otfCacheMeta is stored next to the compiled pack in the cache directory. Hash is the hash of the compiled
+// pack, so that a pack that does not belong to the metadata, such as after a crash while writing the cache,
+// is never served with the wrong key.
+type otfCacheMeta struct {
+	Source   string `json:"source"`
+	Revision string `json:"revision"`
+	Key      string `json:"key"`
+	Hash     string `json:"hash"`
+}
+
+// cachePaths returns the paths of the compiled pack and its metadata in the cache directory. They are named
+// after the source, so that OTFs of different sources may share a cache directory.
+func (o *OTF) cachePaths() (packPath, metaPath string) {
+	name := "otf-" + hex.EncodeToString(sha256([]byte(fmt.Sprint(o.source))))[:16]
+	return filepath.Join(o.cacheDir, name+".zip"), filepath.Join(o.cacheDir, name+".json")
+}
+
+// loadCache returns the compiled pack stored in the cache directory, its archive and its metadata.
+func (o *OTF) loadCache() (*resource.Pack, []byte, otfCacheMeta, error) {
+	packPath, metaPath := o.cachePaths()
+	metaBytes, err := os.ReadFile(metaPath)
+	if err != nil {
+		return nil, nil, otfCacheMeta{}, err
+	}
+	var meta otfCacheMeta
+	if err := json.Unmarshal(metaBytes, &meta); err != nil {
+		return nil, nil, otfCacheMeta{}, fmt.Errorf("decode %s: %w", metaPath, err)
+	}
+	if meta.Source != fmt.Sprint(o.source) {
+		return nil, nil, otfCacheMeta{}, fmt.Errorf("cache is of source %s", meta.Source)
+	}
+	data, err := os.ReadFile(packPath)
+	if err != nil {
+		return nil, nil, otfCacheMeta{}, err
+	}
+	if hex.EncodeToString(sha256(data)) != meta.Hash {
+		return nil, nil, otfCacheMeta{}, errors.New("cached pack does not match its metadata")
+	}
+	pack, err := resource.Read(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, otfCacheMeta{}, fmt.Errorf("read cached pack: %w", err)
+	}
+	return pack, data, meta, nil
+}
+
+// saveCache stores the compiled pack passed in the cache directory, with its key and revision.
+func (o *OTF) saveCache(data []byte, key, revision string) error {
+	if err := os.MkdirAll(o.cacheDir, 0700); err != nil {
+		return err
+	}
+	meta, err := json.Marshal(otfCacheMeta{
+		Source:   fmt.Sprint(o.source),
+		Revision: revision,
+		Key:      key,
+		Hash:     hex.EncodeToString(sha256(data)),
+	})
+	if err != nil {
+		return err
+	}
+	packPath, metaPath := o.cachePaths()
+	if err := writeFileAtomic(packPath, data); err != nil {
+		return err
+	}
+	return writeFileAtomic(metaPath, meta)
+}
+
+// writeFileAtomic writes the data to the file at the path given, readable only by the current user. The file
+// is written to a temporary file first, so that it is never left partially written.
+func writeFileAtomic(path string, data []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		_ = os.Remove(f.Name())
+	}
+	return err
+}
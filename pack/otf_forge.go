@@ -0,0 +1,364 @@
+package pack
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	gitHubAPIURL  = "https://api.github.com"
+	gitLabBaseURL = "https://gitlab.com"
+)
+
+// GitHubSource is a PackSource that builds the pack from a GitHub repository. It follows the head of a branch,
+// the newest tag matching TagPattern, or an asset of a release if ReleaseAsset is set.
+type GitHubSource struct {
+	// APIURL is the URL of the GitHub API. It defaults to https://api.github.com, and is
+	// https://<host>/api/v3 for GitHub Enterprise Server.
+	APIURL string
+	Owner  string
+	Repo   string
+	Branch string
+	// Subdir is an optional directory of the repository, or of the release asset, that holds the pack.
+	Subdir string
+	// TagPattern, if set, builds the pack from the newest tag matching the pattern, such as "v*", instead of
+	// the branch. The pattern uses the syntax of path.Match and is matched against the whole name of the tag.
+	// Tags are ordered by the semantic version in their name. Tags without one, and pre-releases such as
+	// "v1.2.0-rc1", are ignored.
+	TagPattern string
+	// ReleaseAsset, if set, builds the pack from the zip archive attached with the name given to the latest
+	// release, or to the release of the newest tag matching TagPattern if set.
+	ReleaseAsset string
+	// Token is an optional personal access token, required for private repositories unless App is set.
+	Token string
+	// App, if set, authenticates requests as an installation of a GitHub App instead of using Token.
+	App *GitHubApp
+	// Client is the HTTP client used for requests. It defaults to a client with a timeout of 2 minutes.
+	Client *http.Client
+
+	cache httpCache
+}
+
+// String ...
+func (s *GitHubSource) String() string {
+	ref := s.Branch
+	switch {
+	case s.ReleaseAsset != "":
+		ref = "release asset " + s.ReleaseAsset
+	case s.TagPattern != "":
+		ref = "tag " + s.TagPattern
+	}
+	if s.Subdir != "" {
+		ref += ":" + s.Subdir
+	}
+	return s.Owner + "/" + s.Repo + ":" + ref
+}
+
+// Revision ...
+func (s *GitHubSource) Revision(ctx context.Context) (string, error) {
+	header, err := s.header(ctx)
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case s.ReleaseAsset != "":
+		return s.releaseAssetRevision(ctx, header)
+	case s.TagPattern != "":
+		tag, err := s.newestTag(ctx, header)
+		if err != nil {
+			return "", err
+		}
+		return tag.Commit.SHA, nil
+	}
+
+	var commits []struct {
+		SHA string `json:"sha"`
+	}
+	if err := s.cache.getJSON(ctx, s.Client, s.repoURL()+"/commits?sha="+url.QueryEscape(s.Branch)+"&per_page=1", header, &commits); err != nil {
+		return "", err
+	}
+	if len(commits) == 0 {
+		return "", fmt.Errorf("no commits found for branch: %s", s.Branch)
+	}
+	return commits[0].SHA, nil
+}
+
+// Fetch ...
+func (s *GitHubSource) Fetch(ctx context.Context, revision string) (*ResourcePack, error) {
+	header, err := s.header(ctx)
+	if err != nil {
+		return nil, err
+	}
+	u := s.repoURL() + "/zipball/" + url.PathEscape(revision)
+	if s.ReleaseAsset != "" {
+		// The revision of a release asset is the tag of the release followed by the ID of the asset.
+		u = s.repoURL() + "/releases/assets/" + revision[strings.LastIndex(revision, ":")+1:]
+		header.Set("Accept", "application/octet-stream")
+	}
+	data, err := httpGetBytes(ctx, s.Client, u, header)
+	if err != nil {
+		return nil, err
+	}
+	return loadPackArchive(data, s.Subdir)
+}
+
+// gitHubTag is a tag as returned by the GitHub API.
+type gitHubTag struct {
+	Name   string `json:"name"`
+	Commit struct {
+		SHA string `json:"sha"`
+	} `json:"commit"`
+}
+
+// newestTag returns the tag matching the tag pattern with the highest semantic version.
+func (s *GitHubSource) newestTag(ctx context.Context, header http.Header) (gitHubTag, error) {
+	var (
+		newest        gitHubTag
+		newestVersion Version
+		found         bool
+	)
+	// Tags are listed in pages of at most 100, which are followed using the Link header.
+	for u := s.repoURL() + "/tags?per_page=100"; u != ""; {
+		respHeader, body, err := s.cache.get(ctx, s.Client, u, header)
+		if err != nil {
+			return gitHubTag{}, err
+		}
+		var tags []gitHubTag
+		if err := json.Unmarshal(body, &tags); err != nil {
+			return gitHubTag{}, err
+		}
+		for _, tag := range tags {
+			if ok, err := path.Match(s.TagPattern, tag.Name); err != nil {
+				return gitHubTag{}, fmt.Errorf("tag pattern: %w", err)
+			} else if !ok {
+				continue
+			}
+			v, ok := tagVersion(tag.Name)
+			if ok && v.Suffix == "" && (!found || v.Compare(newestVersion) > 0) {
+				newest, newestVersion, found = tag, v, true
+			}
+		}
+		u = nextPageURL(respHeader)
+	}
+	if !found {
+		return gitHubTag{}, fmt.Errorf("no tags found matching %s", s.TagPattern)
+	}
+	return newest, nil
+}
+
+// releaseAssetRevision returns the revision of the release asset, which is the tag of the release followed by
+// the ID of the asset. Replacing an asset gives it a new ID.
+func (s *GitHubSource) releaseAssetRevision(ctx context.Context, header http.Header) (string, error) {
+	u := s.repoURL() + "/releases/latest"
+	if s.TagPattern != "" {
+		tag, err := s.newestTag(ctx, header)
+		if err != nil {
+			return "", err
+		}
+		u = s.repoURL() + "/releases/tags/" + url.PathEscape(tag.Name)
+	}
+	var release struct {
+		TagName string `json:"tag_name"`
+		Assets  []struct {
+			ID   int64  `json:"id"`
+			Name string `json:"name"`
+		} `json:"assets"`
+	}
+	if err := s.cache.getJSON(ctx, s.Client, u, header, &release); err != nil {
+		return "", err
+	}
+	for _, asset := range release.Assets {
+		if asset.Name == s.ReleaseAsset {
+			return release.TagName + ":" + strconv.FormatInt(asset.ID, 10), nil
+		}
+	}
+	return "", fmt.Errorf("release %s has no asset named %s", release.TagName, s.ReleaseAsset)
+}
+
+func (s *GitHubSource) repoURL() string {
+	return s.baseURL() + "/repos/" + url.PathEscape(s.Owner) + "/" + url.PathEscape(s.Repo)
+}
+
+func (s *GitHubSource) baseURL() string {
+	if s.APIURL == "" {
+		return gitHubAPIURL
+	}
+	return strings.TrimSuffix(s.APIURL, "/")
+}
+
+// header returns the headers of requests to the GitHub API, authenticated by the app or token if set.
+func (s *GitHubSource) header(ctx context.Context) (http.Header, error) {
+	header := http.Header{}
+	token := s.Token
+	if s.App != nil {
+		var err error
+		if token, err = s.App.installationToken(ctx, s.Client, s.baseURL()); err != nil {
+			return nil, fmt.Errorf("authenticate github app: %w", err)
+		}
+	}
+	if token != "" {
+		header.Set("Authorization", "Bearer "+token)
+	}
+	header.Set("X-GitHub-Api-Version", "2022-11-28")
+	return header, nil
+}
+
+// GitLabSource is a PackSource that builds the pack from the head of a branch of a GitLab project.
+type GitLabSource struct {
+	// BaseURL is the URL of the GitLab instance. It defaults to https://gitlab.com.
+	BaseURL string
+	// Project is the path of the project, such as "group/project", or its numeric ID.
+	Project string
+	Branch  string
+	// Subdir is an optional directory of the repository that holds the pack.
+	Subdir string
+	// Token is an optional access token, required for private projects.
+	Token string
+	// Client is the HTTP client used for requests. It defaults to a client with a timeout of 2 minutes.
+	Client *http.Client
+
+	cache httpCache
+}
+
+// String ...
+func (s *GitLabSource) String() string {
+	return s.Project + ":" + s.Branch
+}
+
+// Revision ...
+func (s *GitLabSource) Revision(ctx context.Context) (string, error) {
+	var branch struct {
+		Commit struct {
+			ID string `json:"id"`
+		} `json:"commit"`
+	}
+	if err := s.cache.getJSON(ctx, s.Client, s.projectURL()+"/repository/branches/"+url.PathEscape(s.Branch), s.header(), &branch); err != nil {
+		return "", err
+	}
+	if branch.Commit.ID == "" {
+		return "", fmt.Errorf("no commits found for branch: %s", s.Branch)
+	}
+	return branch.Commit.ID, nil
+}
+
+// Fetch ...
+func (s *GitLabSource) Fetch(ctx context.Context, revision string) (*ResourcePack, error) {
+	data, err := httpGetBytes(ctx, s.Client, s.projectURL()+"/repository/archive.zip?sha="+url.QueryEscape(revision), s.header())
+	if err != nil {
+		return nil, err
+	}
+	return loadPackArchive(data, s.Subdir)
+}
+
+func (s *GitLabSource) projectURL() string {
+	baseURL := s.BaseURL
+	if baseURL == "" {
+		baseURL = gitLabBaseURL
+	}
+	return strings.TrimSuffix(baseURL, "/") + "/api/v4/projects/" + url.PathEscape(s.Project)
+}
+
+func (s *GitLabSource) header() http.Header {
+	header := http.Header{}
+	if s.Token != "" {
+		header.Set("PRIVATE-TOKEN", s.Token)
+	}
+	return header
+}
+
+// GiteaSource is a PackSource that builds the pack from the head of a branch of a repository on a Gitea or
+// Forgejo instance.
+type GiteaSource struct {
+	// BaseURL is the URL of the Gitea instance, such as https://gitea.example.com.
+	BaseURL string
+	Owner   string
+	Repo    string
+	Branch  string
+	// Subdir is an optional directory of the repository that holds the pack.
+	Subdir string
+	// Token is an optional access token, required for private repositories.
+	Token string
+	// Client is the HTTP client used for requests. It defaults to a client with a timeout of 2 minutes.
+	Client *http.Client
+
+	cache httpCache
+}
+
+// String ...
+func (s *GiteaSource) String() string {
+	return s.Owner + "/" + s.Repo + ":" + s.Branch
+}
+
+// Revision ...
+func (s *GiteaSource) Revision(ctx context.Context) (string, error) {
+	repoURL, err := s.repoURL()
+	if err != nil {
+		return "", err
+	}
+	var branch struct {
+		Commit struct {
+			ID string `json:"id"`
+		} `json:"commit"`
+	}
+	if err := s.cache.getJSON(ctx, s.Client, repoURL+"/branches/"+url.PathEscape(s.Branch), s.header(), &branch); err != nil {
+		return "", err
+	}
+	if branch.Commit.ID == "" {
+		return "", fmt.Errorf("no commits found for branch: %s", s.Branch)
+	}
+	return branch.Commit.ID, nil
+}
+
+// Fetch ...
+func (s *GiteaSource) Fetch(ctx context.Context, revision string) (*ResourcePack, error) {
+	repoURL, err := s.repoURL()
+	if err != nil {
+		return nil, err
+	}
+	data, err := httpGetBytes(ctx, s.Client, repoURL+"/archive/"+url.PathEscape(revision)+".zip", s.header())
+	if err != nil {
+		return nil, err
+	}
+	return loadPackArchive(data, s.Subdir)
+}
+
+func (s *GiteaSource) repoURL() (string, error) {
+	if s.BaseURL == "" {
+		return "", errors.New("gitea base URL not set")
+	}
+	return fmt.Sprintf("%s/api/v1/repos/%s/%s", strings.TrimSuffix(s.BaseURL, "/"), url.PathEscape(s.Owner), url.PathEscape(s.Repo)), nil
+}
+
+func (s *GiteaSource) header() http.Header {
+	header := http.Header{}
+	if s.Token != "" {
+		header.Set("Authorization", "token "+s.Token)
+	}
+	return header
+}
+
+// tagVersion returns the semantic version in the name of a tag, such as "v1.2.0" or "rp/1.2.0".
+func tagVersion(name string) (Version, bool) {
+	name = name[strings.LastIndex(name, "/")+1:]
+	v, err := ParseVersion(strings.TrimPrefix(name, "v"))
+	return v, err == nil
+}
+
+// linkNextRegex matches the URL of the next page in a Link header.
+var linkNextRegex = regexp.MustCompile(`<([^>]+)>;\s*rel="next"`)
+
+// nextPageURL returns the URL of the next page of a paginated response, or an empty string if it is the last.
+func nextPageURL(header http.Header) string {
+	if m := linkNextRegex.FindStringSubmatch(header.Get("Link")); m != nil {
+		return m[1]
+	}
+	return ""
+}
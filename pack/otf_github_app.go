@@ -0,0 +1,135 @@
+package pack
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	sha256lib "crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// gitHubAppTokenMargin is how long before its expiry an installation token is refreshed.
+const gitHubAppTokenMargin = 5 * time.Minute
+
+// GitHubApp authenticates requests to the GitHub API as an installation of a GitHub App. It signs a JWT with
+// the private key of the app and exchanges it for an installation token, which is refreshed automatically
+// before it expires.
+type GitHubApp struct {
+	// AppID is the ID of the app, or its client ID.
+	AppID string
+	// InstallationID is the ID of the installation of the app on the account that owns the repository.
+	InstallationID int64
+	// PrivateKey is a private key of the app, in PEM format as downloaded from GitHub.
+	PrivateKey []byte
+
+	mu      sync.Mutex
+	key     *rsa.PrivateKey
+	token   string
+	expires time.Time
+	clock   clock
+}
+
+// installationToken returns an installation token for the API at the URL given, requesting a new one with the
+// client passed if the current token expires soon.
+func (a *GitHubApp) installationToken(ctx context.Context, client *http.Client, apiURL string) (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.clock == nil {
+		a.clock = realClock{}
+	}
+	now := a.clock.Now()
+	if a.token != "" && now.Add(gitHubAppTokenMargin).Before(a.expires) {
+		return a.token, nil
+	}
+
+	jwt, err := a.jwt(now)
+	if err != nil {
+		return "", err
+	}
+	u := fmt.Sprintf("%s/app/installations/%d/access_tokens", apiURL, a.InstallationID)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+jwt)
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+
+	resp, err := httpDo(client, req, http.StatusCreated)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	var token struct {
+		Token     string    `json:"token"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", err
+	}
+	if token.Token == "" {
+		return "", errors.New("no installation token returned")
+	}
+	a.token, a.expires = token.Token, token.ExpiresAt
+	return a.token, nil
+}
+
+// jwt returns a JWT that authenticates as the app, valid for 9 minutes from the time passed. It must be called
+// with a.mu held.
+func (a *GitHubApp) jwt(now time.Time) (string, error) {
+	if a.key == nil {
+		key, err := parseRSAPrivateKey(a.PrivateKey)
+		if err != nil {
+			return "", err
+		}
+		a.key = key
+	}
+
+	// The issue time is set in the past to allow for clock drift, as recommended by GitHub.
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"RS256","typ":"JWT"}`))
+	claims, err := json.Marshal(map[string]any{
+		"iat": now.Add(-time.Minute).Unix(),
+		"exp": now.Add(9 * time.Minute).Unix(),
+		"iss": a.AppID,
+	})
+	if err != nil {
+		return "", err
+	}
+	signed := header + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256lib.Sum256([]byte(signed))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, a.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+// parseRSAPrivateKey parses an RSA private key in PEM format, encoded either as PKCS #1, as GitHub provides
+// it, or as PKCS #8.
+func parseRSAPrivateKey(pemBytes []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(pemBytes)
+	if block == nil {
+		return nil, errors.New("private key is not in PEM format")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parse private key: %w", err)
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("private key is not an RSA key")
+	}
+	return rsaKey, nil
+}
@@ -0,0 +1,112 @@
+package pack
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	sha256lib "crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestGitHubAppAuthentication(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clk := newFakeClock()
+
+	var mu sync.Mutex
+	issued := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.URL.Path {
+		case "/api/v3/app/installations/42/access_tokens":
+			if err := verifyTestJWT(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "), &key.PublicKey, clk.Now()); err != nil {
+				t.Errorf("invalid jwt: %v", err)
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			issued++
+			w.WriteHeader(http.StatusCreated)
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"token":      fmt.Sprintf("token-%d", issued),
+				"expires_at": clk.Now().Add(time.Hour),
+			})
+		case "/api/v3/repos/org/repo/commits":
+			if r.Header.Get("Authorization") != fmt.Sprintf("Bearer token-%d", issued) {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			_, _ = w.Write([]byte(`[{"sha":"abc"}]`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	app := &GitHubApp{AppID: "1234", InstallationID: 42, PrivateKey: keyPEM, clock: clk}
+	source := &GitHubSource{APIURL: srv.URL + "/api/v3/", Owner: "org", Repo: "repo", Branch: "main", App: app}
+
+	for i := 0; i < 2; i++ {
+		if rev, err := source.Revision(context.Background()); err != nil || rev != "abc" {
+			t.Fatalf("unexpected revision %s, %v", rev, err)
+		}
+	}
+	if issued != 1 {
+		t.Fatalf("issued %d tokens, want the token to be reused", issued)
+	}
+
+	// The token is refreshed shortly before it expires.
+	clk.Advance(56 * time.Minute)
+	if _, err := source.Revision(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if issued != 2 {
+		t.Fatalf("issued %d tokens, want the token to be refreshed", issued)
+	}
+}
+
+// verifyTestJWT verifies the signature and claims of a JWT issued by the test app at the time given.
+func verifyTestJWT(jwt string, key *rsa.PublicKey, now time.Time) error {
+	parts := strings.Split(jwt, ".")
+	if len(parts) != 3 {
+		return fmt.Errorf("malformed jwt %q", jwt)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return err
+	}
+	digest := sha256lib.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], sig); err != nil {
+		return err
+	}
+	claimBytes, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return err
+	}
+	var claims struct {
+		Iat int64  `json:"iat"`
+		Exp int64  `json:"exp"`
+		Iss string `json:"iss"`
+	}
+	if err := json.Unmarshal(claimBytes, &claims); err != nil {
+		return err
+	}
+	if claims.Iss != "1234" || claims.Iat > now.Unix() || claims.Exp <= now.Unix() || claims.Exp-claims.Iat > 600 {
+		return fmt.Errorf("unexpected claims %+v", claims)
+	}
+	return nil
+}
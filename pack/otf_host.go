@@ -0,0 +1,196 @@
+package pack
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/sandertv/gophertunnel/minecraft/resource"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PackHost hosts compiled packs for clients to download over HTTP, instead of the server sending them.
+type PackHost interface {
+	// Put stores the pack archive passed under the name given and returns the URL from which clients download
+	// it.
+	Put(ctx context.Context, name string, data []byte) (string, error)
+	// Delete removes the pack archive stored under the name given.
+	Delete(ctx context.Context, name string) error
+}
+
+// MemoryPackHost is a PackHost that keeps packs in memory and serves them over HTTP. It may be mounted on an
+// existing HTTP server, or served by OTF by setting OTFConfig.PackAddr.
+type MemoryPackHost struct {
+	// BaseURL is the public URL at which clients reach the host, such as "https://packs.example.com".
+	BaseURL string
+
+	mu    sync.RWMutex
+	packs map[string][]byte
+}
+
+// Put ...
+func (h *MemoryPackHost) Put(_ context.Context, name string, data []byte) (string, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.packs == nil {
+		h.packs = map[string][]byte{}
+	}
+	h.packs[name] = data
+	return joinURL(h.BaseURL, name), nil
+}
+
+// Delete ...
+func (h *MemoryPackHost) Delete(_ context.Context, name string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.packs, name)
+	return nil
+}
+
+// ServeHTTP serves the pack named by the last element of the path of the request.
+func (h *MemoryPackHost) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	name := path.Base(r.URL.Path)
+	h.mu.RLock()
+	data, ok := h.packs[name]
+	h.mu.RUnlock()
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "application/zip")
+	http.ServeContent(w, r, name, time.Time{}, bytes.NewReader(data))
+}
+
+// HTTPUploadPackHost is a PackHost that uploads packs with PUT requests and removes them with DELETE requests,
+// such as to a WebDAV server or the storage of a CDN.
+type HTTPUploadPackHost struct {
+	// UploadURL is the base URL to which packs are uploaded.
+	UploadURL string
+	// PublicURL is the base URL from which clients download uploaded packs. It defaults to UploadURL.
+	PublicURL string
+	// Header holds optional headers sent with every request, such as Authorization.
+	Header http.Header
+	// Client is the HTTP client used for requests. It defaults to a client with a timeout of 2 minutes.
+	Client *http.Client
+}
+
+// Put ...
+func (h *HTTPUploadPackHost) Put(ctx context.Context, name string, data []byte) (string, error) {
+	req, err := h.request(ctx, http.MethodPut, name, data)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/zip")
+	resp, err := httpDo(h.Client, req, http.StatusOK, http.StatusCreated, http.StatusNoContent)
+	if err != nil {
+		return "", err
+	}
+	_ = resp.Body.Close()
+
+	publicURL := h.PublicURL
+	if publicURL == "" {
+		publicURL = h.UploadURL
+	}
+	return joinURL(publicURL, name), nil
+}
+
+// Delete ...
+func (h *HTTPUploadPackHost) Delete(ctx context.Context, name string) error {
+	req, err := h.request(ctx, http.MethodDelete, name, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := httpDo(h.Client, req, http.StatusOK, http.StatusAccepted, http.StatusNoContent, http.StatusNotFound)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+func (h *HTTPUploadPackHost) request(ctx context.Context, method, name string, body []byte) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, joinURL(h.UploadURL, name), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range h.Header {
+		req.Header[key] = values
+	}
+	return req, nil
+}
+
+// joinURL returns the URL of the file with the name given under the base URL passed.
+func joinURL(baseURL, name string) string {
+	return strings.TrimSuffix(baseURL, "/") + "/" + url.PathEscape(name)
+}
+
+// readPackURL reads the pack at the URL passed with resource.ReadURL, which sets the URL clients download the
+// pack from. ReadURL downloads the pack with the default HTTP client, without a context, so it is given up on
+// once the context passed is done or the timeout of the client passed has passed.
+func readPackURL(ctx context.Context, client *http.Client, u string) (*resource.Pack, error) {
+	if client == nil {
+		client = defaultHTTPClient
+	}
+	if client.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, client.Timeout)
+		defer cancel()
+	}
+	type result struct {
+		pack *resource.Pack
+		err  error
+	}
+	// The channel is buffered, so that the goroutine does not block once the result is no longer waited for.
+	results := make(chan result, 1)
+	go func() {
+		pack, err := resource.ReadURL(u)
+		results <- result{pack: pack, err: err}
+	}()
+	select {
+	case r := <-results:
+		return r.pack, r.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// nestPackArchive returns the zip archive passed with all its files moved into the directory given. Clients
+// that download a pack from a URL expect the pack in a directory of the archive rather than at its root. The
+// files are copied without being compressed again.
+func nestPackArchive(data []byte, dir string) ([]byte, error) {
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	writer := zip.NewWriter(&buf)
+	for _, f := range reader.File {
+		raw, err := f.OpenRaw()
+		if err != nil {
+			return nil, err
+		}
+		header := f.FileHeader
+		header.Name = dir + "/" + f.Name
+		w, err := writer.CreateRaw(&header)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.Copy(w, raw); err != nil {
+			return nil, err
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("close archive: %w", err)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,179 @@
+package pack
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestOTFPackHost(t *testing.T) {
+	host := &MemoryPackHost{}
+	hostSrv := httptest.NewServer(host)
+	defer hostSrv.Close()
+	host.BaseURL = hostSrv.URL + "/packs"
+
+	gh := &fakeGitHub{t: t}
+	gh.setCommit("aaa", testRepoFiles(t, "Test"))
+	srv := httptest.NewServer(gh)
+	defer srv.Close()
+	source := &GitHubSource{APIURL: srv.URL, Owner: "org", Repo: "repo", Branch: "main"}
+	o := OTFConfig{Source: source, PollInterval: time.Minute, PackHost: host}.New(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	clk := newFakeClock()
+	o.clock = clk
+	if err := o.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer o.Stop()
+
+	first, _ := currentPack(o)
+	if !strings.HasPrefix(first.DownloadURL(), host.BaseURL+"/") {
+		t.Fatalf("unexpected download URL %q", first.DownloadURL())
+	}
+	resp, err := http.Get(first.DownloadURL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range reader.File {
+		if !strings.HasPrefix(f.Name, first.UUID().String()+"/") {
+			t.Fatalf("file %s of hosted pack is not in a directory named after the pack", f.Name)
+		}
+	}
+
+	// The previous pack stays hosted after an update, and is removed after the next.
+	clk.BlockUntil(1)
+	for _, name := range []string{"Second", "Third"} {
+		gh.setCommit(name, testRepoFiles(t, name))
+		clk.Advance(time.Minute)
+		clk.BlockUntil(1)
+	}
+	host.mu.RLock()
+	n := len(host.packs)
+	host.mu.RUnlock()
+	if n != 2 {
+		t.Fatalf("host holds %d packs, want the current and the previous pack", n)
+	}
+	resp, err = http.Get(first.DownloadURL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("first pack still hosted, got status %d", resp.StatusCode)
+	}
+
+	// Packs below the minimum size are sent by the server.
+	o.packURLMinSize = 1 << 30
+	gh.setCommit("small", testRepoFiles(t, "Small"))
+	clk.Advance(time.Minute)
+	clk.BlockUntil(1)
+	if p, _ := currentPack(o); p.DownloadURL() != "" {
+		t.Fatalf("pack below the minimum size hosted at %s", p.DownloadURL())
+	}
+}
+
+func TestHTTPUploadPackHost(t *testing.T) {
+	var mu sync.Mutex
+	files := map[string][]byte{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.Header.Get("AccessKey") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.Method {
+		case http.MethodPut:
+			files[r.URL.Path], _ = io.ReadAll(r.Body)
+			w.WriteHeader(http.StatusCreated)
+		case http.MethodDelete:
+			delete(files, r.URL.Path)
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer srv.Close()
+
+	host := &HTTPUploadPackHost{
+		UploadURL: srv.URL + "/zone/",
+		PublicURL: "https://cdn.example.com/packs",
+		Header:    http.Header{"AccessKey": {"secret"}},
+	}
+	u, err := host.Put(context.Background(), "pack.zip", []byte("data"))
+	if err != nil || u != "https://cdn.example.com/packs/pack.zip" {
+		t.Fatalf("unexpected URL %s, %v", u, err)
+	}
+	if string(files["/zone/pack.zip"]) != "data" {
+		t.Fatalf("pack not uploaded: %v", files)
+	}
+	if err := host.Delete(context.Background(), "pack.zip"); err != nil || len(files) != 0 {
+		t.Fatalf("pack not deleted: %v, %v", files, err)
+	}
+
+	host.Header = nil
+	if _, err := host.Put(context.Background(), "pack.zip", []byte("data")); err == nil {
+		t.Error("expected error for an unauthorized upload")
+	}
+}
+
+// stalledPackHost is a PackHost of which the download URLs never respond.
+type stalledPackHost struct {
+	url string
+}
+
+// Put ...
+func (h stalledPackHost) Put(context.Context, string, []byte) (string, error) {
+	return h.url, nil
+}
+
+// Delete ...
+func (h stalledPackHost) Delete(context.Context, string) error {
+	return nil
+}
+
+func TestOTFPackHostStalled(t *testing.T) {
+	release := make(chan struct{})
+	stalled := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer stalled.Close()
+	defer close(release)
+
+	dir := t.TempDir()
+	writeTestFiles(t, dir, testRepoFiles(t, "Test"))
+	o := OTFConfig{
+		Source:     &LocalDirSource{Path: dir},
+		PackHost:   stalledPackHost{url: stalled.URL + "/pack.zip"},
+		HTTPClient: &http.Client{Timeout: 100 * time.Millisecond},
+	}.New(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	o.clock = newFakeClock()
+
+	started := make(chan error, 1)
+	go func() {
+		started <- o.Start(context.Background())
+	}()
+	select {
+	case err := <-started:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("build blocked on a stalled pack host")
+	}
+	o.Stop()
+	if p, _ := currentPack(o); p == nil || p.DownloadURL() != "" {
+		t.Fatal("pack of a stalled host not sent by the server")
+	}
+}
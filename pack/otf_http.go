@@ -0,0 +1,178 @@
+package pack
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// otfHTTPTimeout is the timeout of requests of the default HTTP client of pack sources.
+const otfHTTPTimeout = 2 * time.Minute
+
+// defaultHTTPClient is the HTTP client of pack sources that do not set one.
+var defaultHTTPClient = &http.Client{Timeout: otfHTTPTimeout}
+
+// httpStatusError is returned for responses with an unexpected status. RetryAfter is set if the server asked
+// to wait before the next request, either by Retry-After or by the rate limit headers of GitHub.
+type httpStatusError struct {
+	Method     string
+	URL        string
+	StatusCode int
+	RetryAfter time.Duration
+}
+
+// Error ...
+func (e *httpStatusError) Error() string {
+	if e.RetryAfter > 0 {
+		return fmt.Sprintf("%s %s returned status %d, retry after %v", e.Method, e.URL, e.StatusCode, e.RetryAfter)
+	}
+	return fmt.Sprintf("%s %s returned status %d", e.Method, e.URL, e.StatusCode)
+}
+
+// retryAfter returns how long the server of the response asked to wait before the next request, or 0 if it did
+// not.
+func retryAfter(resp *http.Response) time.Duration {
+	if v := resp.Header.Get("Retry-After"); v != "" {
+		if seconds, err := strconv.Atoi(v); err == nil {
+			return time.Duration(seconds) * time.Second
+		}
+		if t, err := http.ParseTime(v); err == nil {
+			return time.Until(t)
+		}
+	}
+	// GitHub answers with 403 or 429 once the rate limit is used up, and resets it at the Unix time given.
+	if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+		if reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+			return time.Until(time.Unix(reset, 0))
+		}
+	}
+	return 0
+}
+
+// transientError reports whether the error passed is likely to go away when retried, such as a network error,
+// a server error or a rate limit.
+func transientError(err error) bool {
+	var statusErr *httpStatusError
+	if errors.As(err, &statusErr) {
+		switch statusErr.StatusCode {
+		case http.StatusRequestTimeout, http.StatusTooManyRequests:
+			return true
+		}
+		return statusErr.StatusCode >= 500 || statusErr.RetryAfter > 0
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// httpDo sends the request using the client passed, or the default client if nil. It returns an
+// *httpStatusError if the status of the response is not one of the statuses given.
+func httpDo(client *http.Client, req *http.Request, statuses ...int) (*http.Response, error) {
+	if client == nil {
+		client = defaultHTTPClient
+	}
+	req.Header.Set("User-Agent", otfUserAgent)
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	for _, status := range statuses {
+		if resp.StatusCode == status {
+			return resp, nil
+		}
+	}
+	_ = resp.Body.Close()
+	return nil, &httpStatusError{Method: req.Method, URL: req.URL.String(), StatusCode: resp.StatusCode, RetryAfter: max(retryAfter(resp), 0)}
+}
+
+// httpGet sends a GET request to the URL given with the headers passed. It returns an error if the status of
+// the response is not 200 OK or 304 Not Modified.
+func httpGet(ctx context.Context, client *http.Client, url string, header http.Header) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	return httpDo(client, req, http.StatusOK, http.StatusNotModified)
+}
+
+// httpGetBytes sends a GET request to the URL given and returns the body of the response.
+func httpGetBytes(ctx context.Context, client *http.Client, url string, header http.Header) ([]byte, error) {
+	resp, err := httpGet(ctx, client, url, header)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return io.ReadAll(resp.Body)
+}
+
+// httpCache holds the ETag, headers and body of responses, so that requests for the same URL are sent with
+// If-None-Match. Responses of 304 Not Modified are answered from the cache, and do not count towards the rate
+// limit of GitHub.
+type httpCache struct {
+	mu      sync.Mutex
+	entries map[string]httpCacheEntry
+}
+
+type httpCacheEntry struct {
+	etag   string
+	header http.Header
+	body   []byte
+}
+
+// get sends a conditional GET request to the URL given and returns the headers and body of the response, or of
+// the cached response if it has not changed.
+func (c *httpCache) get(ctx context.Context, client *http.Client, url string, header http.Header) (http.Header, []byte, error) {
+	c.mu.Lock()
+	entry, cached := c.entries[url]
+	c.mu.Unlock()
+
+	header = header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	if cached {
+		header.Set("If-None-Match", entry.etag)
+	}
+	resp, err := httpGet(ctx, client, url, header)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotModified {
+		if !cached {
+			return nil, nil, fmt.Errorf("GET %s returned status 304 without a conditional request", url)
+		}
+		return entry.header, entry.body, nil
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	if etag := resp.Header.Get("ETag"); etag != "" {
+		c.mu.Lock()
+		if c.entries == nil {
+			c.entries = map[string]httpCacheEntry{}
+		}
+		c.entries[url] = httpCacheEntry{etag: etag, header: resp.Header, body: body}
+		c.mu.Unlock()
+	}
+	return resp.Header, body, nil
+}
+
+// getJSON sends a conditional GET request to the URL given and decodes the JSON response into v.
+func (c *httpCache) getJSON(ctx context.Context, client *http.Client, url string, header http.Header, v any) error {
+	_, body, err := c.get(ctx, client, url, header)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(body, v)
+}
@@ -0,0 +1,234 @@
+package pack
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"github.com/sandertv/gophertunnel/minecraft"
+	"github.com/sandertv/gophertunnel/minecraft/resource"
+	"log/slog"
+	"slices"
+	"sync"
+)
+
+// OTFManager runs an OTF for each of a set of pack sources and keeps their packs on a listener in the order of
+// their priority. Packs with a higher priority come first in the pack stack sent to clients, where they override
+// the packs after them. Each pack is updated independently, and the order is kept on every update.
+type OTFManager struct {
+	log   *slog.Logger
+	clock clock
+
+	// mu guards the fields below. It is held while the listener is changed, so that the packs on the listener
+	// always match stack. An OTF holds its own lock while calling its slot, so mu must never be held while
+	// calling an OTF.
+	mu           sync.Mutex
+	listener     *minecraft.Listener
+	packListener packListener
+	slots        map[string]*otfSlot
+	seq          int
+	// stack holds the packs of the manager on the listener, in the order they were added to it.
+	stack []*resource.Pack
+}
+
+// otfSlot is a pack managed by an OTFManager. It is the listener of the OTF of the pack, through which the
+// manager tracks the packs of the OTF.
+type otfSlot struct {
+	m        *OTFManager
+	name     string
+	priority int
+	// seq is the order in which the slot was added, which orders slots of the same priority.
+	seq   int
+	otf   *OTF
+	packs []*resource.Pack
+}
+
+// NewOTFManager returns an OTFManager without packs.
+func NewOTFManager(log *slog.Logger) *OTFManager {
+	return &OTFManager{log: log, clock: realClock{}, slots: map[string]*otfSlot{}}
+}
+
+// Add starts an OTF with the config passed for a pack with the name and priority given, and adds its pack to
+// the listener once built. It returns an error if a pack with the name already exists or if the OTF fails to
+// start. The OTF runs until the context passed is cancelled, or until the pack is removed or the manager is
+// stopped.
+func (m *OTFManager) Add(ctx context.Context, name string, priority int, conf OTFConfig) error {
+	o := conf.New(m.log.With("pack", name))
+	o.clock = m.clock
+
+	m.mu.Lock()
+	if _, ok := m.slots[name]; ok {
+		m.mu.Unlock()
+		return fmt.Errorf("pack %s already exists", name)
+	}
+	m.seq++
+	slot := &otfSlot{m: m, name: name, priority: priority, seq: m.seq, otf: o}
+	m.slots[name] = slot
+	m.mu.Unlock()
+
+	o.setPackListener(nil, slot)
+	if err := o.Start(ctx); err != nil {
+		m.mu.Lock()
+		delete(m.slots, name)
+		m.sync()
+		m.mu.Unlock()
+		return fmt.Errorf("start pack %s: %w", name, err)
+	}
+	return nil
+}
+
+// Remove stops the OTF of the pack with the name given and removes its pack from the listener. It returns false
+// if there is no pack with the name.
+func (m *OTFManager) Remove(name string) bool {
+	m.mu.Lock()
+	slot, ok := m.slots[name]
+	if ok {
+		delete(m.slots, name)
+		m.sync()
+	}
+	m.mu.Unlock()
+	if ok {
+		// The OTF is stopped without holding the lock, as an update in progress needs it to finish.
+		slot.otf.Stop()
+	}
+	return ok
+}
+
+// SetPriority changes the priority of the pack with the name given and reorders the packs on the listener. It
+// returns false if there is no pack with the name.
+func (m *OTFManager) SetPriority(name string, priority int) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	slot, ok := m.slots[name]
+	if ok {
+		slot.priority = priority
+		m.sync()
+	}
+	return ok
+}
+
+// OTF returns the OTF of the pack with the name given, or nil if there is no pack with the name.
+func (m *OTFManager) OTF(name string) *OTF {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if slot, ok := m.slots[name]; ok {
+		return slot.otf
+	}
+	return nil
+}
+
+// SetListener sets the listener to serve the packs on, and adds the current packs to it in order.
+func (m *OTFManager) SetListener(listener *minecraft.Listener) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.listener = listener
+	// A nil listener must not be stored as a non-nil interface.
+	m.packListener = nil
+	if listener != nil {
+		m.packListener = listener
+	}
+	m.stack = nil
+	m.sync()
+}
+
+// Listener ...
+func (m *OTFManager) Listener() *minecraft.Listener {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.listener
+}
+
+// Stop stops the OTFs of all packs. The packs remain on the listener.
+func (m *OTFManager) Stop() {
+	m.mu.Lock()
+	slots := make([]*otfSlot, 0, len(m.slots))
+	for _, slot := range m.slots {
+		slots = append(slots, slot)
+	}
+	m.mu.Unlock()
+	for _, slot := range slots {
+		slot.otf.Stop()
+	}
+}
+
+// AddResourcePack ...
+func (s *otfSlot) AddResourcePack(pack *resource.Pack) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	s.packs = append(s.packs, pack)
+	s.m.sync()
+}
+
+// RemoveResourcePack ...
+func (s *otfSlot) RemoveResourcePack(uuid string) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	s.packs = slices.DeleteFunc(s.packs, func(pack *resource.Pack) bool {
+		return pack.UUID().String() == uuid
+	})
+	s.m.sync()
+}
+
+// sync brings the packs on the listener in line with the packs of the slots, in the order of their priority.
+// The listener only appends packs, so packs out of place are moved to the end one at a time, each removed and
+// added again right away, so that the listener lacks at most one of the packs it keeps at any time. New packs
+// are added before old packs are removed. It must be called with m.mu held.
+func (m *OTFManager) sync() {
+	desired := m.desiredStack()
+	if m.packListener == nil {
+		m.stack = nil
+		return
+	}
+
+	// The longest start of the desired order that is already in order on the listener is kept, and the packs
+	// after it are added to the end in the desired order.
+	kept := 0
+	for _, pack := range m.stack {
+		if kept < len(desired) && pack.UUID() == desired[kept].UUID() {
+			kept++
+		}
+	}
+	for _, pack := range desired[kept:] {
+		if containsPack(m.stack, pack) {
+			m.packListener.RemoveResourcePack(pack.UUID().String())
+		}
+		m.packListener.AddResourcePack(pack)
+	}
+	for _, pack := range m.stack {
+		if !containsPack(desired, pack) {
+			m.packListener.RemoveResourcePack(pack.UUID().String())
+		}
+	}
+	m.stack = desired
+}
+
+// desiredStack returns the packs of all slots in the order of the priority of the slots, highest first. Packs
+// of the same UUID, which the listener cannot tell apart, are only included once. It must be called with m.mu
+// held.
+func (m *OTFManager) desiredStack() []*resource.Pack {
+	slots := make([]*otfSlot, 0, len(m.slots))
+	for _, slot := range m.slots {
+		slots = append(slots, slot)
+	}
+	slices.SortFunc(slots, func(a, b *otfSlot) int {
+		if c := cmp.Compare(b.priority, a.priority); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.seq, b.seq)
+	})
+	var stack []*resource.Pack
+	for _, slot := range slots {
+		for _, pack := range slot.packs {
+			if !containsPack(stack, pack) {
+				stack = append(stack, pack)
+			}
+		}
+	}
+	return stack
+}
+
+// containsPack reports whether the packs passed contain a pack with the UUID of the pack given.
+func containsPack(packs []*resource.Pack, pack *resource.Pack) bool {
+	return slices.ContainsFunc(packs, func(p *resource.Pack) bool {
+		return p.UUID() == pack.UUID()
+	})
+}
@@ -0,0 +1,103 @@
+package pack
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestOTFManagerOrder(t *testing.T) {
+	m := NewOTFManager(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	clk := newFakeClock()
+	m.clock = clk
+	l := &fakeListener{}
+	m.packListener = l
+	defer m.Stop()
+
+	dirs := map[string]string{}
+	for i, name := range []string{"main", "ui", "event"} {
+		dirs[name] = t.TempDir()
+		writeTestFiles(t, dirs[name], testRepoFiles(t, name))
+		conf := OTFConfig{Source: &LocalDirSource{Path: dirs[name]}, PollInterval: time.Minute}
+		if err := m.Add(context.Background(), name, []int{0, 10, 5}[i], conf); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := m.Add(context.Background(), "main", 0, OTFConfig{Source: &LocalDirSource{Path: dirs["main"]}}); err == nil {
+		t.Fatal("expected error adding a pack with an existing name")
+	}
+	uuid := func(name string) string {
+		p, _ := currentPack(m.OTF(name))
+		return p.UUID().String()
+	}
+	expectOrder := func(names ...string) {
+		t.Helper()
+		want := make([]string, len(names))
+		for i, name := range names {
+			want[i] = uuid(name)
+		}
+		if got := l.uuids(); !slices.Equal(got, want) {
+			t.Fatalf("listener holds %v, want %v (%v)", got, want, names)
+		}
+	}
+	expectOrder("ui", "event", "main")
+
+	// Of the packs that are in the desired stack both before and after a change, at most one may be missing
+	// from the listener after any call made to it during the change, while it is moved.
+	l.mu.Lock()
+	change, before := len(l.states), l.uuidsLocked()
+	l.mu.Unlock()
+	expectKept := func() {
+		t.Helper()
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		after := l.uuidsLocked()
+		for _, state := range l.states[change:] {
+			missing := 0
+			for _, uuid := range before {
+				if slices.Contains(after, uuid) && !slices.Contains(state, uuid) {
+					missing++
+				}
+			}
+			if missing > 1 {
+				t.Fatalf("listener lacked %v packs kept during change: %v", missing, l.states[change:])
+			}
+		}
+		change, before = len(l.states), after
+	}
+
+	// An update of a pack in the middle of the stack keeps its place.
+	oldEvent := uuid("event")
+	writeTestFiles(t, dirs["event"], map[string][]byte{"texts/en_US.lang": []byte("pack.name=Updated")})
+	clk.BlockUntil(3)
+	clk.Advance(time.Minute)
+	clk.BlockUntil(3)
+	if uuid("event") == oldEvent {
+		t.Fatal("event pack not updated")
+	}
+	expectOrder("ui", "event", "main")
+	expectKept()
+
+	if !m.SetPriority("main", 20) {
+		t.Fatal("main pack not found")
+	}
+	expectOrder("main", "ui", "event")
+	expectKept()
+
+	if !m.Remove("ui") || m.Remove("ui") {
+		t.Fatal("unexpected result removing ui pack")
+	}
+	expectOrder("main", "event")
+	expectKept()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for i, n := range l.history {
+		if n == 0 {
+			t.Fatalf("listener had no pack after change %d: %v", i, l.history)
+		}
+	}
+}
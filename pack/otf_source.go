@@ -0,0 +1,217 @@
+package pack
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os/exec"
+	"path"
+	"strings"
+	"sync"
+)
+
+// PackSource is where OTF gets the pack from, such as a Git repository.
+type PackSource interface {
+	// Revision returns an identifier of the current revision of the pack, such as a commit hash. OTF rebuilds
+	// the pack when it changes.
+	Revision(ctx context.Context) (string, error)
+	// Fetch returns the pack at a revision returned by Revision.
+	Fetch(ctx context.Context, revision string) (*ResourcePack, error)
+}
+
+// HTTPZipSource is a PackSource that downloads the pack as a zip archive from a URL. If the server sends an
+// ETag, it is used as the revision and the archive is only downloaded again once it changes. Otherwise, the
+// archive is downloaded on every check and its hash is used as the revision.
+type HTTPZipSource struct {
+	URL string
+	// Subdir is an optional directory of the archive that holds the pack.
+	Subdir string
+	// Header holds optional headers sent with every request, such as Authorization.
+	Header http.Header
+	// Client is the HTTP client used to download the archive. It defaults to a client with a timeout of 2
+	// minutes.
+	Client *http.Client
+
+	mu       sync.Mutex
+	etag     string
+	revision string
+	body     []byte
+}
+
+// String ...
+func (s *HTTPZipSource) String() string {
+	return s.URL
+}
+
+// Revision ...
+func (s *HTTPZipSource) Revision(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	header := s.Header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	if s.etag != "" {
+		header.Set("If-None-Match", s.etag)
+	}
+	resp, err := httpGet(ctx, s.Client, s.URL, header)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotModified {
+		return s.revision, nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	s.etag, s.body = resp.Header.Get("ETag"), body
+	s.revision = s.etag
+	if s.revision == "" {
+		s.revision = hex.EncodeToString(sha256(body))
+	}
+	return s.revision, nil
+}
+
+// Fetch ...
+func (s *HTTPZipSource) Fetch(_ context.Context, revision string) (*ResourcePack, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if revision != s.revision || s.body == nil {
+		return nil, fmt.Errorf("revision %s of %s is no longer available", revision, s.URL)
+	}
+	// The archive is kept until the revision changes, as a build that fails after fetching it is retried with
+	// the same revision.
+	return loadPackArchive(s.body, s.Subdir)
+}
+
+// LocalDirSource is a PackSource that reads the pack from a local directory. The revision is a hash of the
+// files of the pack, so that the pack is rebuilt whenever a file changes.
+type LocalDirSource struct {
+	Path string
+}
+
+// String ...
+func (s *LocalDirSource) String() string {
+	return s.Path
+}
+
+// Revision ...
+func (s *LocalDirSource) Revision(context.Context) (string, error) {
+	rp, err := LoadResourcePackFromDir(s.Path)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(rp.ComputeHash()), nil
+}
+
+// Fetch ...
+func (s *LocalDirSource) Fetch(context.Context, string) (*ResourcePack, error) {
+	return LoadResourcePackFromDir(s.Path)
+}
+
+// LocalGitSource is a PackSource that reads the pack from a ref of a local Git repository, using the git
+// command.
+type LocalGitSource struct {
+	Path string
+	// Ref is the branch, tag or other ref to build the pack from. It defaults to HEAD.
+	Ref string
+	// Subdir is an optional directory of the repository that holds the pack.
+	Subdir string
+}
+
+// String ...
+func (s *LocalGitSource) String() string {
+	return s.Path + ":" + s.ref()
+}
+
+// Revision ...
+func (s *LocalGitSource) Revision(ctx context.Context) (string, error) {
+	out, err := s.git(ctx, "rev-parse", "--verify", s.ref()+"^{commit}")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// Fetch ...
+func (s *LocalGitSource) Fetch(ctx context.Context, revision string) (*ResourcePack, error) {
+	out, err := s.git(ctx, "archive", "--format=zip", revision)
+	if err != nil {
+		return nil, err
+	}
+	return loadPackArchive(out, s.Subdir)
+}
+
+func (s *LocalGitSource) ref() string {
+	if s.Ref == "" {
+		return "HEAD"
+	}
+	return s.Ref
+}
+
+// git runs git in the repository with the arguments given and returns its output.
+func (s *LocalGitSource) git(ctx context.Context, args ...string) ([]byte, error) {
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-C", s.Path}, args...)...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
+
+// loadPackArchive loads the pack in the directory given of a zip archive, or the pack at the root of the
+// archive if the directory is empty. Archives of Git hosts hold the repository in a single top-level
+// directory, in which case the directory given is relative to that directory.
+func loadPackArchive(data []byte, dir string) (*ResourcePack, error) {
+	dir = strings.Trim(path.Clean("/"+dir), "/")
+	if dir == "" {
+		return LoadResourcePackFromBytes(data)
+	}
+	dir += "/"
+
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+	files := map[string][]byte{}
+	for _, f := range reader.File {
+		name, ok := strings.CutPrefix(f.Name, dir)
+		if !ok {
+			_, rest, _ := strings.Cut(f.Name, "/")
+			if name, ok = strings.CutPrefix(rest, dir); !ok {
+				continue
+			}
+		}
+		if name == "" || strings.HasSuffix(name, "/") {
+			continue
+		}
+		file, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		content, err := io.ReadAll(file)
+		_ = file.Close()
+		if err != nil {
+			return nil, err
+		}
+		files[name] = content
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("directory %s not found in archive", dir)
+	}
+	rp := &ResourcePack{}
+	if err := rp.loadFiles(files); err != nil {
+		return nil, err
+	}
+	return rp, nil
+}
@@ -0,0 +1,299 @@
+package pack
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testZip returns a zip archive of the files given, each placed under the prefix passed.
+func testZip(t *testing.T, prefix string, files map[string][]byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	arc := zip.NewWriter(&buf)
+	for fileName, fileBytes := range files {
+		w, err := arc.Create(prefix + fileName)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write(fileBytes); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := arc.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// writeTestFiles writes the files given to the directory passed.
+func writeTestFiles(t *testing.T, dir string, files map[string][]byte) {
+	t.Helper()
+	for fileName, fileBytes := range files {
+		filePath := filepath.Join(dir, filepath.FromSlash(fileName))
+		if err := os.MkdirAll(filepath.Dir(filePath), 0777); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filePath, fileBytes, 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestLocalDirSource(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFiles(t, dir, testRepoFiles(t, "Test"))
+
+	o, clk := newTestSourceOTF(&LocalDirSource{Path: dir}, time.Minute)
+	if err := o.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer o.Stop()
+	first, rev := currentPack(o)
+
+	writeTestFiles(t, dir, map[string][]byte{"texts/en_US.lang": []byte("pack.name=Updated")})
+	clk.BlockUntil(1)
+	clk.Advance(time.Minute)
+	clk.BlockUntil(1)
+	second, newRev := currentPack(o)
+	if newRev == rev || second.UUID() == first.UUID() {
+		t.Fatal("change of local directory not picked up")
+	}
+}
+
+func TestLocalGitSource(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	git := func(args ...string) string {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-C", dir, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, out)
+		}
+		return string(bytes.TrimSpace(out))
+	}
+	git("init", "-q")
+	writeTestFiles(t, dir, testRepoFiles(t, "Test"))
+	git("add", "-A")
+	git("commit", "-q", "-m", "initial")
+
+	source := &LocalGitSource{Path: dir}
+	rev, err := source.Revision(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if head := git("rev-parse", "HEAD"); rev != head {
+		t.Fatalf("got revision %s, want %s", rev, head)
+	}
+
+	// Uncommitted changes are not part of the revision.
+	writeTestFiles(t, dir, map[string][]byte{"texts/en_US.lang": []byte("pack.name=Updated")})
+	rp, err := source.Fetch(context.Background(), rev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lang, _ := rp.loadFile("texts/en_US.lang"); string(lang) != "pack.name=Test" {
+		t.Fatalf("unexpected content %q", lang)
+	}
+
+	git("commit", "-q", "-am", "update")
+	if newRev, err := source.Revision(context.Background()); err != nil || newRev == rev {
+		t.Fatalf("revision not updated after commit: %s, %v", newRev, err)
+	}
+}
+
+func TestHTTPZipSource(t *testing.T) {
+	var mu sync.Mutex
+	etag, downloads := `"v1"`, 0
+	archive := testZip(t, "", testRepoFiles(t, "Test"))
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		downloads++
+		w.Header().Set("ETag", etag)
+		_, _ = w.Write(archive)
+	}))
+	defer srv.Close()
+
+	source := &HTTPZipSource{URL: srv.URL}
+	ctx := context.Background()
+	rev, err := source.Revision(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := source.Fetch(ctx, rev); err != nil {
+		t.Fatal(err)
+	}
+	if again, err := source.Revision(ctx); err != nil || again != rev {
+		t.Fatalf("revision changed without a new ETag: %s, %v", again, err)
+	}
+	// A build that failed after fetching the archive may fetch it again.
+	if _, err := source.Fetch(ctx, rev); err != nil {
+		t.Fatalf("fetch of unchanged revision after a 304: %v", err)
+	}
+
+	mu.Lock()
+	etag = `"v2"`
+	mu.Unlock()
+	newRev, err := source.Revision(ctx)
+	if err != nil || newRev != `"v2"` {
+		t.Fatalf("unexpected revision %s, %v", newRev, err)
+	}
+	if _, err := source.Fetch(ctx, rev); err == nil {
+		t.Error("expected error fetching an old revision")
+	}
+	if downloads != 2 {
+		t.Errorf("archive downloaded %d times, want 2", downloads)
+	}
+}
+
+func TestForgeSources(t *testing.T) {
+	archive := testZip(t, "repo-abc/", testRepoFiles(t, "Test"))
+	tests := map[string]struct {
+		source func(baseURL string) PackSource
+		routes map[string]string
+		auth   [2]string
+	}{
+		"gitlab": {
+			source: func(baseURL string) PackSource {
+				return &GitLabSource{BaseURL: baseURL, Project: "group/repo", Branch: "main", Token: "secret"}
+			},
+			routes: map[string]string{
+				"/api/v4/projects/group%2Frepo/repository/branches/main": `{"commit":{"id":"abc"}}`,
+				"/api/v4/projects/group%2Frepo/repository/archive.zip":   "",
+			},
+			auth: [2]string{"PRIVATE-TOKEN", "secret"},
+		},
+		"gitea": {
+			source: func(baseURL string) PackSource {
+				return &GiteaSource{BaseURL: baseURL, Owner: "org", Repo: "repo", Branch: "main", Token: "secret"}
+			},
+			routes: map[string]string{
+				"/api/v1/repos/org/repo/branches/main":   `{"commit":{"id":"abc"}}`,
+				"/api/v1/repos/org/repo/archive/abc.zip": "",
+			},
+			auth: [2]string{"Authorization", "token secret"},
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, ok := test.routes[r.URL.EscapedPath()]
+				if !ok || r.Header.Get(test.auth[0]) != test.auth[1] {
+					http.NotFound(w, r)
+					return
+				}
+				if body == "" {
+					_, _ = w.Write(archive)
+					return
+				}
+				_, _ = w.Write([]byte(body))
+			}))
+			defer srv.Close()
+
+			source := test.source(srv.URL)
+			rev, err := source.Revision(context.Background())
+			if err != nil || rev != "abc" {
+				t.Fatalf("unexpected revision %s, %v", rev, err)
+			}
+			rp, err := source.Fetch(context.Background(), rev)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if rp.UUID() == "" {
+				t.Error("pack has no UUID")
+			}
+		})
+	}
+}
+
+func TestGitHubSourceTags(t *testing.T) {
+	// The pack is in a subdirectory of a monorepo, next to files that are not part of it.
+	files := map[string][]byte{"README.md": []byte("# Monorepo"), "server/main.go": []byte("package main")}
+	for name, content := range testRepoFiles(t, "Test") {
+		files["packs/rp/"+name] = content
+	}
+	archive := testZip(t, "org-repo-ccc/", files)
+
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/org/repo/tags":
+			if r.URL.Query().Get("page") == "2" {
+				_, _ = w.Write([]byte(`[{"name":"v1.10.0","commit":{"sha":"ccc"}},{"name":"v2.0.0-rc1","commit":{"sha":"ddd"}}]`))
+				return
+			}
+			w.Header().Set("Link", `<`+srv.URL+`/repos/org/repo/tags?per_page=100&page=2>; rel="next"`)
+			_, _ = w.Write([]byte(`[{"name":"v1.9.0","commit":{"sha":"aaa"}},{"name":"nightly","commit":{"sha":"bbb"}},{"name":"server/v3.0.0","commit":{"sha":"eee"}}]`))
+		case "/repos/org/repo/zipball/ccc":
+			_, _ = w.Write(archive)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	source := &GitHubSource{APIURL: srv.URL, Owner: "org", Repo: "repo", Subdir: "packs/rp", TagPattern: "v*"}
+	rev, err := source.Revision(context.Background())
+	if err != nil || rev != "ccc" {
+		t.Fatalf("got revision %s, %v, want the newest release tag v1.10.0", rev, err)
+	}
+	rp, err := source.Fetch(context.Background(), rev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rp.loadFile("server/main.go"); err == nil {
+		t.Error("files outside the subdirectory are part of the pack")
+	}
+	if lang, _ := rp.loadFile("texts/en_US.lang"); string(lang) != "pack.name=Test" {
+		t.Fatalf("unexpected content %q", lang)
+	}
+}
+
+func TestGitHubSourceReleaseAsset(t *testing.T) {
+	archive := testZip(t, "", testRepoFiles(t, "Test"))
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/org/repo/releases/latest":
+			_, _ = w.Write([]byte(`{"tag_name":"v1.2.0","assets":[{"id":7,"name":"other.zip"},{"id":8,"name":"pack.zip"}]}`))
+		case "/repos/org/repo/releases/assets/8":
+			if r.Header.Get("Accept") != "application/octet-stream" {
+				w.WriteHeader(http.StatusNotAcceptable)
+				return
+			}
+			_, _ = w.Write(archive)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	source := &GitHubSource{APIURL: srv.URL, Owner: "org", Repo: "repo", ReleaseAsset: "pack.zip"}
+	rev, err := source.Revision(context.Background())
+	if err != nil || rev != "v1.2.0:8" {
+		t.Fatalf("unexpected revision %s, %v", rev, err)
+	}
+	if _, err := source.Fetch(context.Background(), rev); err != nil {
+		t.Fatal(err)
+	}
+
+	source.ReleaseAsset = "missing.zip"
+	if _, err := source.Revision(context.Background()); err == nil {
+		t.Error("expected error for a missing release asset")
+	}
+}
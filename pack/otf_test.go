@@ -0,0 +1,545 @@
+package pack
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/sandertv/gophertunnel/minecraft/resource"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock is a clock of which the time only moves when Advance is called.
+type fakeClock struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	now     time.Time
+	waiters []fakeWaiter
+}
+
+type fakeWaiter struct {
+	deadline time.Time
+	c        chan time.Time
+}
+
+func newFakeClock() *fakeClock {
+	c := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	c.cond = sync.NewCond(&c.mu)
+	return c
+}
+
+// Now ...
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// After ...
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ch := make(chan time.Time, 1)
+	c.waiters = append(c.waiters, fakeWaiter{deadline: c.now.Add(d), c: ch})
+	c.cond.Broadcast()
+	return ch
+}
+
+// Advance moves the time forward, firing every channel returned by After of which the deadline passed.
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	waiters := c.waiters[:0]
+	for _, w := range c.waiters {
+		if w.deadline.After(c.now) {
+			waiters = append(waiters, w)
+			continue
+		}
+		w.c <- c.now
+	}
+	c.waiters = waiters
+}
+
+// BlockUntil waits until n calls to After are waiting for their deadline.
+func (c *fakeClock) BlockUntil(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for len(c.waiters) != n {
+		c.cond.Wait()
+	}
+}
+
+// fakeGitHub is a stub of the GitHub API that serves a repository of which the head commit may be changed.
+// Requests for the head commit fail with the statuses in failures first, if any.
+type fakeGitHub struct {
+	t           *testing.T
+	mu          sync.Mutex
+	commit      string
+	archive     []byte
+	commits     int
+	notModified int
+	failures    []int
+}
+
+func (g *fakeGitHub) setCommit(commit string, files map[string][]byte) {
+	archive := testZip(g.t, "org-repo-"+commit+"/", files)
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.commit, g.archive = commit, archive
+}
+
+// ServeHTTP ...
+func (g *fakeGitHub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	switch {
+	case r.URL.Path == "/repos/org/repo/commits":
+		g.commits++
+		if len(g.failures) > 0 {
+			status := g.failures[0]
+			g.failures = g.failures[1:]
+			if status == http.StatusForbidden {
+				// The rate limit is used up and resets in 30 minutes.
+				w.Header().Set("X-RateLimit-Remaining", "0")
+				w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(30*time.Minute).Unix(), 10))
+			}
+			w.WriteHeader(status)
+			return
+		}
+		etag := `"` + g.commit + `"`
+		if r.Header.Get("If-None-Match") == etag {
+			g.notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		_ = json.NewEncoder(w).Encode([]map[string]string{{"sha": g.commit}})
+	case r.URL.Path == "/repos/org/repo/zipball/"+g.commit:
+		// Archives of GitHub hold the repository in a directory named after the commit.
+		_, _ = w.Write(g.archive)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// currentPack returns the current pack of the OTF and the commit it was built from.
+func currentPack(o *OTF) (*resource.Pack, string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.currentPack, o.currentPackRevision
+}
+
+// fakeListener records the packs added to it, and the number of packs and the UUIDs of the packs it had after
+// every change.
+type fakeListener struct {
+	mu      sync.Mutex
+	packs   []*resource.Pack
+	history []int
+	states  [][]string
+}
+
+// AddResourcePack ...
+func (l *fakeListener) AddResourcePack(pack *resource.Pack) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.packs = append(l.packs, pack)
+	l.record()
+}
+
+// RemoveResourcePack ...
+func (l *fakeListener) RemoveResourcePack(uuid string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.packs = slices.DeleteFunc(l.packs, func(pack *resource.Pack) bool {
+		return pack.UUID().String() == uuid
+	})
+	l.record()
+}
+
+// record records the packs on the listener after a change. It must be called with l.mu held.
+func (l *fakeListener) record() {
+	l.history = append(l.history, len(l.packs))
+	l.states = append(l.states, l.uuidsLocked())
+}
+
+// uuids returns the UUIDs of the packs on the listener.
+func (l *fakeListener) uuids() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.uuidsLocked()
+}
+
+// uuidsLocked returns the UUIDs of the packs on the listener. It must be called with l.mu held.
+func (l *fakeListener) uuidsLocked() []string {
+	uuids := make([]string, 0, len(l.packs))
+	for _, pack := range l.packs {
+		uuids = append(uuids, pack.UUID().String())
+	}
+	return uuids
+}
+
+// testRepoFiles returns the files of a repository holding a pack with the name given.
+func testRepoFiles(t *testing.T, name string) map[string][]byte {
+	t.Helper()
+	icon, err := placeholderIcon(PackTypeResource)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return map[string][]byte{
+		"manifest.json":             []byte(testManifest),
+		"pack_icon.png":             icon,
+		"textures/blocks/stone.png": icon,
+		"models/entity/test.json":   []byte(`{"format_version": "1.12.0"}`),
+		"texts/en_US.lang":          []byte("pack.name=" + name),
+		"README.md":                 []byte("# " + name),
+	}
+}
+
+// newTestOTF returns an OTF that polls the stub GitHub API passed using a fake clock.
+func newTestOTF(t *testing.T, gh http.Handler, interval time.Duration) (*OTF, *fakeClock) {
+	t.Helper()
+	srv := httptest.NewServer(gh)
+	t.Cleanup(srv.Close)
+	return newTestSourceOTF(&GitHubSource{Owner: "org", Repo: "repo", Branch: "main", APIURL: srv.URL}, interval)
+}
+
+// newTestSourceOTF returns an OTF that polls the source passed using a fake clock.
+func newTestSourceOTF(source PackSource, interval time.Duration) (*OTF, *fakeClock) {
+	o := OTFConfig{Source: source, PollInterval: interval}.New(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	clk := newFakeClock()
+	o.clock = clk
+	return o, clk
+}
+
+func TestOTFPollsOnSchedule(t *testing.T) {
+	gh := &fakeGitHub{t: t}
+	gh.setCommit("aaa", testRepoFiles(t, "Test"))
+
+	o, clk := newTestOTF(t, gh, time.Minute)
+	if err := o.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer o.Stop()
+	first, commit := currentPack(o)
+	if commit != "aaa" || first == nil {
+		t.Fatalf("pack not built on start, commit %q", commit)
+	}
+
+	gh.setCommit("bbb", testRepoFiles(t, "Updated"))
+
+	clk.BlockUntil(1)
+	clk.Advance(59 * time.Second)
+	gh.mu.Lock()
+	commits := gh.commits
+	gh.mu.Unlock()
+	if commits != 1 {
+		t.Fatalf("checked for updates %d times before the poll interval passed", commits)
+	}
+
+	clk.Advance(time.Second)
+	// The loop waits for the next poll again once the update is done.
+	clk.BlockUntil(1)
+	second, commit := currentPack(o)
+	if commit != "bbb" {
+		t.Fatalf("update not picked up after poll interval, commit %q", commit)
+	}
+	if second.UUID() == first.UUID() {
+		t.Error("pack UUID did not change with its content")
+	}
+
+	// An unchanged commit does not rebuild the pack.
+	clk.Advance(time.Minute)
+	clk.BlockUntil(1)
+	if p, _ := currentPack(o); p != second {
+		t.Error("pack rebuilt without a new commit")
+	}
+}
+
+func TestOTFStop(t *testing.T) {
+	gh := &fakeGitHub{t: t}
+	gh.setCommit("aaa", testRepoFiles(t, "Test"))
+
+	o, clk := newTestOTF(t, gh, time.Minute)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := o.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := o.Start(ctx); err == nil {
+		t.Error("expected error when starting twice")
+	}
+	clk.BlockUntil(1)
+
+	stopped := make(chan struct{})
+	go func() {
+		o.Stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Stop did not return")
+	}
+	// Stopping again, or after the context is cancelled, is a no-op.
+	cancel()
+	o.Stop()
+
+	gh.mu.Lock()
+	defer gh.mu.Unlock()
+	if gh.commits != 1 {
+		t.Errorf("unexpected checks after stop: %d", gh.commits)
+	}
+}
+
+func TestOTFStopsOnContextCancel(t *testing.T) {
+	gh := &fakeGitHub{t: t}
+	gh.setCommit("aaa", testRepoFiles(t, "Test"))
+
+	o, _ := newTestOTF(t, gh, time.Minute)
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := o.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	select {
+	case <-o.done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("poll loop did not stop on context cancel")
+	}
+	if _, commit := currentPack(o); commit != "aaa" {
+		t.Errorf("unexpected commit %q", commit)
+	}
+}
+
+func TestOTFSwapKeepsPackOnListener(t *testing.T) {
+	gh := &fakeGitHub{t: t}
+	gh.setCommit("aaa", testRepoFiles(t, "Test"))
+	o, clk := newTestOTF(t, gh, time.Minute)
+	l := &fakeListener{}
+	o.mu.Lock()
+	o.packListener = l
+	o.mu.Unlock()
+	if err := o.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer o.Stop()
+
+	// Read the state concurrently with the updates, like a server accepting players would.
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				_ = o.Listener()
+				_, _ = currentPack(o)
+				_ = l.uuids()
+			}
+		}
+	}()
+
+	for i, name := range []string{"Second", "Third"} {
+		gh.setCommit(name, testRepoFiles(t, name))
+		clk.BlockUntil(1)
+		clk.Advance(time.Minute)
+		clk.BlockUntil(1)
+		p, _ := currentPack(o)
+		if uuids := l.uuids(); len(uuids) != 1 || uuids[0] != p.UUID().String() {
+			t.Fatalf("update %d: listener holds %v, want only %s", i, uuids, p.UUID())
+		}
+	}
+
+	// A commit that leaves the pack unchanged keeps the pack on the listener.
+	files := testRepoFiles(t, "Third")
+	files["README.md"] = []byte("changed")
+	gh.setCommit("readme", files)
+	clk.Advance(time.Minute)
+	clk.BlockUntil(1)
+	if uuids := l.uuids(); len(uuids) != 1 {
+		t.Fatalf("listener holds %v after a commit without pack changes", uuids)
+	}
+	close(stop)
+	wg.Wait()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for i, n := range l.history {
+		if n == 0 {
+			t.Fatalf("listener had no pack after change %d: %v", i, l.history)
+		}
+	}
+}
+
+func TestOTFConditionalRevisionCheck(t *testing.T) {
+	gh := &fakeGitHub{t: t}
+	gh.setCommit("aaa", testRepoFiles(t, "Test"))
+	o, clk := newTestOTF(t, gh, time.Minute)
+	if err := o.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer o.Stop()
+
+	for i := 0; i < 3; i++ {
+		clk.BlockUntil(1)
+		clk.Advance(time.Minute)
+	}
+	clk.BlockUntil(1)
+	gh.mu.Lock()
+	defer gh.mu.Unlock()
+	if gh.commits != 4 || gh.notModified != 3 {
+		t.Fatalf("%d of %d checks answered with 304 Not Modified, want 3 of 4", gh.notModified, gh.commits)
+	}
+	if _, rev := currentPack(o); rev != "aaa" {
+		t.Fatalf("unexpected revision %q", rev)
+	}
+}
+
+func TestOTFRetry(t *testing.T) {
+	gh := &fakeGitHub{t: t}
+	gh.setCommit("aaa", testRepoFiles(t, "Test"))
+	o, clk := newTestOTF(t, gh, time.Hour)
+	if err := o.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer o.Stop()
+	checks := func() int {
+		gh.mu.Lock()
+		defer gh.mu.Unlock()
+		return gh.commits
+	}
+
+	// Server errors are retried with backoff, well before the next poll.
+	gh.mu.Lock()
+	gh.failures = []int{http.StatusServiceUnavailable, http.StatusBadGateway}
+	gh.mu.Unlock()
+	gh.setCommit("bbb", testRepoFiles(t, "Updated"))
+	clk.BlockUntil(1)
+	clk.Advance(time.Hour)
+	clk.BlockUntil(1)
+	clk.Advance(otfRetryBaseDelay)
+	clk.BlockUntil(1)
+	clk.Advance(2 * otfRetryBaseDelay)
+	clk.BlockUntil(1)
+	if _, rev := currentPack(o); rev != "bbb" || checks() != 4 {
+		t.Fatalf("revision %q after %d checks, want bbb after 4", rev, checks())
+	}
+
+	// A used up rate limit is waited for until it resets.
+	gh.mu.Lock()
+	gh.failures = []int{http.StatusForbidden}
+	gh.mu.Unlock()
+	gh.setCommit("ccc", testRepoFiles(t, "Third"))
+	clk.Advance(time.Hour)
+	clk.BlockUntil(1)
+	clk.Advance(29 * time.Minute)
+	if n := checks(); n != 5 {
+		t.Fatalf("checked %d times before the rate limit reset", n)
+	}
+	clk.Advance(time.Minute)
+	clk.BlockUntil(1)
+	if _, rev := currentPack(o); rev != "ccc" {
+		t.Fatalf("revision %q after the rate limit reset, want ccc", rev)
+	}
+}
+
+func TestOTFCache(t *testing.T) {
+	dir := t.TempDir()
+	newCachedOTF := func(gh http.Handler) (*OTF, *fakeClock) {
+		srv := httptest.NewServer(gh)
+		t.Cleanup(srv.Close)
+		source := &GitHubSource{APIURL: srv.URL, Owner: "org", Repo: "repo", Branch: "main"}
+		o := OTFConfig{Source: source, PollInterval: time.Hour, CacheDir: dir}.New(slog.New(slog.NewTextHandler(io.Discard, nil)))
+		clk := newFakeClock()
+		o.clock = clk
+		return o, clk
+	}
+
+	gh := &fakeGitHub{t: t}
+	gh.setCommit("aaa", testRepoFiles(t, "Test"))
+	o, _ := newCachedOTF(gh)
+	if err := o.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	o.Stop()
+	built, _ := currentPack(o)
+
+	// The cached pack is served while the source is down.
+	down := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	o, _ = newCachedOTF(down)
+	if err := o.Start(context.Background()); err != nil {
+		t.Fatalf("start with cached pack and source down: %v", err)
+	}
+	o.Stop()
+	if cached, rev := currentPack(o); rev != "aaa" || cached.UUID() != built.UUID() || o.currentPackKey == "" {
+		t.Fatalf("cached pack not served, revision %q", rev)
+	}
+
+	// The cached pack is replaced once the source has a new revision.
+	gh.setCommit("bbb", testRepoFiles(t, "Updated"))
+	o, clk := newCachedOTF(gh)
+	if err := o.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer o.Stop()
+	// The poll loop waits next to the wait that the revalidation interrupted.
+	clk.BlockUntil(2)
+	if _, rev := currentPack(o); rev != "bbb" {
+		t.Fatalf("cached pack not revalidated, revision %q", rev)
+	}
+
+	// A cached pack that does not match its metadata is not served.
+	packPath, _ := o.cachePaths()
+	if err := os.WriteFile(packPath, []byte("corrupt"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	o, _ = newCachedOTF(down)
+	if err := o.Start(context.Background()); err == nil {
+		t.Fatal("expected error starting with a corrupt cache and source down")
+	}
+}
+
+func TestOTFStopDuringStart(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFiles(t, dir, testRepoFiles(t, "Test"))
+	for _, files := range []bool{true, false} {
+		source := &LocalDirSource{Path: dir}
+		if !files {
+			// A start that fails must not leave Stop waiting.
+			source.Path = t.TempDir()
+		}
+		o, _ := newTestSourceOTF(source, time.Minute)
+		o.Stop()
+
+		started := make(chan error, 1)
+		go func() {
+			started <- o.Start(context.Background())
+		}()
+		stopped := make(chan struct{})
+		go func() {
+			o.Stop()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+		case <-time.After(5 * time.Second):
+			t.Fatal("Stop did not return while starting")
+		}
+		if err := <-started; (err == nil) != files {
+			t.Fatalf("unexpected start error %v", err)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package pack
+
+import (
+	"crypto/hmac"
+	sha256lib "crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// webhookMaxBodySize is the maximum size of a webhook payload, which is the limit of GitHub.
+const webhookMaxBodySize = 25 << 20
+
+// WebhookHandler returns a handler of push and release webhooks of GitHub and Gitea. A push to the branch of
+// the source, or a new tag or release if the source follows those, triggers an immediate check for a new
+// revision. Requests are rejected unless signed with the webhook secret
+// of the config.
+func (o *OTF) WebhookHandler() http.Handler {
+	return http.HandlerFunc(o.handleWebhook)
+}
+
+func (o *OTF) handleWebhook(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, webhookMaxBodySize))
+	if err != nil {
+		http.Error(w, "failed to read body", http.StatusBadRequest)
+		return
+	}
+	if !o.validWebhookSignature(r.Header, body) {
+		o.log.Warn("rejected webhook with invalid signature", "remote_addr", r.RemoteAddr)
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	event := r.Header.Get("X-GitHub-Event")
+	if event == "" {
+		event = r.Header.Get("X-Gitea-Event")
+	}
+	if event != "push" && event != "release" {
+		// Other events, such as the ping sent when the webhook is created, are acknowledged but ignored.
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	var payload struct {
+		Ref string `json:"ref"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		http.Error(w, "invalid payload", http.StatusBadRequest)
+		return
+	}
+	if !webhookTriggers(o.source, event, payload.Ref) {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	o.log.Info("received webhook, checking for update", "event", event, "ref", payload.Ref)
+	select {
+	case o.trigger <- struct{}{}:
+	default:
+		// A check is already pending, which will see this push as well.
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// validWebhookSignature reports whether the body is signed with the webhook secret, either in the
+// X-Hub-Signature-256 header of GitHub or the X-Gitea-Signature header of Gitea.
+func (o *OTF) validWebhookSignature(header http.Header, body []byte) bool {
+	if len(o.webhookSecret) == 0 {
+		return false
+	}
+	signature, ok := strings.CutPrefix(header.Get("X-Hub-Signature-256"), "sha256=")
+	if !ok {
+		signature = header.Get("X-Gitea-Signature")
+	}
+	got, err := hex.DecodeString(signature)
+	if err != nil || len(got) == 0 {
+		return false
+	}
+	mac := hmac.New(sha256lib.New, o.webhookSecret)
+	mac.Write(body)
+	return hmac.Equal(got, mac.Sum(nil))
+}
+
+// webhookTriggers reports whether a webhook event for the ref passed may change the revision of the source.
+// Sources that follow tags or releases are triggered by releases and pushed tags, and other sources by pushes to
+// their branch. Events are never filtered for sources without a branch.
+func webhookTriggers(source PackSource, event, ref string) bool {
+	var branch string
+	switch source := source.(type) {
+	case *GitHubSource:
+		if source.TagPattern != "" || source.ReleaseAsset != "" {
+			return event == "release" || strings.HasPrefix(ref, "refs/tags/")
+		}
+		branch = source.Branch
+	case *GitLabSource:
+		branch = source.Branch
+	case *GiteaSource:
+		branch = source.Branch
+	}
+	if branch == "" {
+		return true
+	}
+	return event == "push" && ref == "refs/heads/"+branch
+}
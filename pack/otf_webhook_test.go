@@ -0,0 +1,124 @@
+package pack
+
+import (
+	"context"
+	"crypto/hmac"
+	sha256lib "crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// signWebhook returns the X-Hub-Signature-256 header of GitHub for the body and secret given.
+func signWebhook(body, secret string) string {
+	mac := hmac.New(sha256lib.New, []byte(secret))
+	mac.Write([]byte(body))
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestOTFWebhook(t *testing.T) {
+	gh := &fakeGitHub{t: t}
+	gh.setCommit("aaa", testRepoFiles(t, "Test"))
+	srv := httptest.NewServer(gh)
+	defer srv.Close()
+
+	o := OTFConfig{
+		Source:        &GitHubSource{APIURL: srv.URL, Owner: "org", Repo: "repo", Branch: "main"},
+		WebhookSecret: "s3cret",
+	}.New(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if o.pollInterval != otfWebhookPollInterval {
+		t.Errorf("poll interval %v, want %v with webhooks enabled", o.pollInterval, otfWebhookPollInterval)
+	}
+	clk := newFakeClock()
+	o.clock = clk
+	if err := o.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer o.Stop()
+	clk.BlockUntil(1)
+	gh.setCommit("bbb", testRepoFiles(t, "Updated"))
+
+	otherBranch := `{"ref":"refs/heads/dev"}`
+	push := `{"ref":"refs/heads/main"}`
+	tests := []struct {
+		name, method, event, body, signature string
+		status                               int
+	}{
+		{"get", http.MethodGet, "push", push, signWebhook(push, "s3cret"), http.StatusMethodNotAllowed},
+		{"unsigned", http.MethodPost, "push", push, "", http.StatusUnauthorized},
+		{"wrong secret", http.MethodPost, "push", push, signWebhook(push, "wrong"), http.StatusUnauthorized},
+		{"tampered", http.MethodPost, "push", otherBranch, signWebhook(push, "s3cret"), http.StatusUnauthorized},
+		{"ping", http.MethodPost, "ping", `{}`, signWebhook(`{}`, "s3cret"), http.StatusNoContent},
+		{"other branch", http.MethodPost, "push", otherBranch, signWebhook(otherBranch, "s3cret"), http.StatusNoContent},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(test.method, "/", strings.NewReader(test.body))
+		req.Header.Set("X-GitHub-Event", test.event)
+		if test.signature != "" {
+			req.Header.Set("X-Hub-Signature-256", test.signature)
+		}
+		rec := httptest.NewRecorder()
+		o.WebhookHandler().ServeHTTP(rec, req)
+		if rec.Code != test.status {
+			t.Errorf("%s: got status %d, want %d", test.name, rec.Code, test.status)
+		}
+	}
+	if len(o.trigger) != 0 {
+		t.Fatal("update triggered by an ignored webhook")
+	}
+
+	// Gitea signs the payload without a prefix, in its own header.
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(push))
+	req.Header.Set("X-Gitea-Event", "push")
+	req.Header.Set("X-Gitea-Signature", strings.TrimPrefix(signWebhook(push, "s3cret"), "sha256="))
+	rec := httptest.NewRecorder()
+	o.WebhookHandler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("got status %d, want %d", rec.Code, http.StatusAccepted)
+	}
+
+	// The update happens without the clock moving. The poll loop then waits again, next to the wait that the
+	// webhook interrupted.
+	clk.BlockUntil(2)
+	if _, rev := currentPack(o); rev != "bbb" {
+		t.Fatalf("webhook did not trigger an update, revision %q", rev)
+	}
+}
+
+func TestWebhookTriggers(t *testing.T) {
+	branch := &GitHubSource{Branch: "main"}
+	tags := &GitHubSource{Branch: "main", TagPattern: "v*"}
+	tests := []struct {
+		source     PackSource
+		event, ref string
+		want       bool
+	}{
+		{branch, "push", "refs/heads/main", true},
+		{branch, "push", "refs/heads/dev", false},
+		{branch, "push", "refs/tags/v1.0.0", false},
+		{branch, "release", "", false},
+		{tags, "push", "refs/heads/main", false},
+		{tags, "push", "refs/tags/v1.0.0", true},
+		{tags, "release", "", true},
+		{&LocalDirSource{}, "push", "refs/heads/dev", true},
+	}
+	for _, test := range tests {
+		if got := webhookTriggers(test.source, test.event, test.ref); got != test.want {
+			t.Errorf("webhookTriggers(%v, %s, %s) = %v, want %v", test.source, test.event, test.ref, got, test.want)
+		}
+	}
+}
+
+func TestOTFWebhookAddrRequiresSecret(t *testing.T) {
+	o := OTFConfig{Source: &LocalDirSource{Path: t.TempDir()}, WebhookAddr: "127.0.0.1:0"}.New(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := o.Start(ctx); err == nil || !strings.Contains(err.Error(), "secret") {
+		t.Fatalf("expected error about the missing secret, got %v", err)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"math/rand"
 	"os"
 	"path/filepath"
@@ -20,9 +21,16 @@ type ResourcePack struct {
 	uuid      string
 	files     map[string][]byte
 	encrypted bool
+	// watermark is the fingerprint written to the contents.json header on encryption, if any.
+	watermark []byte
 }
 
+// LoadResourcePack loads the pack at the path given, which may be a zip archive or a directory.
 func LoadResourcePack(path string) (*ResourcePack, error) {
+	if stat, err := os.Stat(path); err == nil && stat.IsDir() {
+		return LoadResourcePackFromDir(path)
+	}
+
 	packBytes, err := os.ReadFile(path)
 	if err != nil {
 		return nil, err
@@ -41,16 +49,42 @@ func LoadResourcePackFromBytes(packBytes []byte) (*ResourcePack, error) {
 	return rp, nil
 }
 
+// LoadResourcePackFromDir loads the pack from the files in the directory at the path given.
+func LoadResourcePackFromDir(path string) (*ResourcePack, error) {
+	files := map[string][]byte{}
+	err := filepath.WalkDir(path, func(filePath string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(path, filePath)
+		if err != nil {
+			return err
+		}
+		content, err := os.ReadFile(filePath)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = content
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	rp := &ResourcePack{}
+	if err := rp.loadFiles(files); err != nil {
+		return nil, err
+	}
+	return rp, nil
+}
+
 func (r *ResourcePack) load(packBytes []byte) error {
 	reader, err := zip.NewReader(bytes.NewReader(packBytes), int64(len(packBytes)))
 	if err != nil {
 		return err
 	}
 
-	manifestFound := false
-	basePath := ""
-
-	r.files = map[string][]byte{}
+	files := map[string][]byte{}
 	for _, fileInfo := range reader.File {
 		file, err := fileInfo.Open()
 		if err != nil {
@@ -60,36 +94,41 @@ func (r *ResourcePack) load(packBytes []byte) error {
 		if err != nil {
 			return err
 		}
-		if filepath.Base(fileInfo.Name) == "manifest.json" {
-			var manifest map[string]any
-			if err := json.Unmarshal(content, &manifest); err != nil {
-				return err
-			}
-			if _, ok := manifest["header"]; !ok {
-				return errors.New("manifest.json header not found")
-			}
-			if _, ok := manifest["header"].(map[string]any); !ok {
-				return errors.New("manifest.json header is not a map[string]any")
-			}
-			if _, ok := manifest["header"].(map[string]any)["uuid"]; !ok {
-				return errors.New("manifest.json header uuid not found")
-			}
-			packUuid, ok := manifest["header"].(map[string]any)["uuid"].(string)
-			if !ok {
-				return errors.New("manifest.json header uuid is not a string")
-			}
-			r.uuid = packUuid
-			manifestFound = true
-			basePath = filepath.Dir(fileInfo.Name)
+		if fileInfo.Name == "" {
+			continue
 		}
+		files[fileInfo.Name] = content
+	}
+	return r.loadFiles(files)
+}
 
-		if fileInfo.Name == "" {
+// loadFiles loads the pack from the files given, keyed by their path in the pack. If the manifest is in a
+// subdirectory, the paths are made relative to that directory.
+func (r *ResourcePack) loadFiles(files map[string][]byte) error {
+	manifestDepth := -1
+	basePath := ""
+
+	r.files = files
+	for fileName, content := range files {
+		if filepath.Base(fileName) != "manifest.json" {
+			continue
+		}
+		// The manifest closest to the root decides the base path, so that manifests of nested packs are not
+		// mistaken for the manifest of the pack.
+		depth := strings.Count(fileName, "/")
+		if manifestDepth != -1 && depth >= manifestDepth {
 			continue
 		}
-		r.files[fileInfo.Name] = content
+		packUuid, err := manifestUUID(content)
+		if err != nil {
+			return err
+		}
+		r.uuid = packUuid
+		manifestDepth = depth
+		basePath = filepath.Dir(fileName)
 	}
 
-	if !manifestFound {
+	if manifestDepth == -1 {
 		return errors.New("manifest.json not found")
 	}
 
@@ -109,26 +148,70 @@ func (r *ResourcePack) load(packBytes []byte) error {
 		}
 	}
 
-	if _, ok := r.files["contents.json"]; ok {
+	if contentsBytes, ok := r.files["contents.json"]; ok {
 		r.encrypted = true
+		// The watermark is kept, so that it is written again when contents.json is rewritten.
+		r.watermark = contentsWatermark(contentsBytes)
 	}
 
 	return nil
 }
 
-type contentJson struct {
-	Content []contentJsonEntry `json:"content"`
+// Clone returns a deep copy of the pack.
+func (r *ResourcePack) Clone() *ResourcePack {
+	files := make(map[string][]byte, len(r.files))
+	for fileName, fileBytes := range r.files {
+		files[fileName] = bytes.Clone(fileBytes)
+	}
+	return &ResourcePack{
+		uuid:      r.uuid,
+		files:     files,
+		encrypted: r.encrypted,
+		watermark: bytes.Clone(r.watermark),
+	}
 }
 
-type contentJsonEntry struct {
-	Path string `json:"path"`
-	Key  string `json:"key"`
+// manifestUUID returns the header UUID of the manifest passed.
+func manifestUUID(manifestBytes []byte) (string, error) {
+	var manifest map[string]any
+	if err := json.Unmarshal(manifestBytes, &manifest); err != nil {
+		return "", err
+	}
+	if _, ok := manifest["header"]; !ok {
+		return "", errors.New("manifest.json header not found")
+	}
+	if _, ok := manifest["header"].(map[string]any); !ok {
+		return "", errors.New("manifest.json header is not a map[string]any")
+	}
+	if _, ok := manifest["header"].(map[string]any)["uuid"]; !ok {
+		return "", errors.New("manifest.json header uuid not found")
+	}
+	packUuid, ok := manifest["header"].(map[string]any)["uuid"].(string)
+	if !ok {
+		return "", errors.New("manifest.json header uuid is not a string")
+	}
+	return packUuid, nil
 }
 
 func (r *ResourcePack) UUID() string {
 	return r.uuid
 }
 
+// Encrypted reports whether the pack is encrypted.
+func (r *ResourcePack) Encrypted() bool {
+	return r.encrypted
+}
+
+// FileNames returns the names of all files in the pack, sorted.
+func (r *ResourcePack) FileNames() []string {
+	fileNames := make([]string, 0, len(r.files))
+	for fileName := range r.files {
+		fileNames = append(fileNames, fileName)
+	}
+	sort.Strings(fileNames)
+	return fileNames
+}
+
 func (r *ResourcePack) DeleteFile(fileName string) {
 	delete(r.files, fileName)
 }
@@ -157,51 +240,70 @@ func (r *ResourcePack) loadFile(fileName string) ([]byte, error) {
 	return fileBytes, nil
 }
 
+// Decrypt decrypts the pack with the key given, including every subpack declared in the manifest.
+// ErrNotEncrypted is returned if the pack is not encrypted and ErrWrongKey if contents.json could not be
+// decrypted with the key. If some files did not decrypt to plausible content or are missing from
+// contents.json, the pack is still decrypted and a *DecryptError is returned.
 func (r *ResourcePack) Decrypt(key []byte) error {
 	if !r.encrypted {
-		return nil
-	}
-
-	contentsBytes, err := r.loadFile("contents.json")
-	if err != nil {
-		return err
+		return ErrNotEncrypted
 	}
 
-	if len(contentsBytes) < 256 {
-		return errors.New("contents.json bytes is less than 256 bytes")
+	// Every contents.json is read before any file is decrypted, so that a wrong key leaves the pack untouched.
+	scopes := r.contentsScopes()
+	contents := make([][]contentJsonEntry, len(scopes))
+	for i, scope := range scopes {
+		if _, ok := r.files[scope.prefix+"contents.json"]; !ok && scope.prefix != "" {
+			continue
+		}
+		entries, err := r.readContents(scope, key)
+		if err != nil {
+			return err
+		}
+		contents[i] = entries
 	}
 
-	contentRaw := contentsBytes[256:]
-	decryptedContents, err := decryptCfb(contentRaw, key)
-	if err != nil {
-		return err
-	}
+	decryptErr := &DecryptError{}
+	listed := map[string]struct{}{}
+	for i, scope := range scopes {
+		for _, content := range contents[i] {
+			filePath := scope.prefix + content.Path
+			listed[filePath] = struct{}{}
+			if content.Key == "" {
+				continue
+			}
+			fileBytes, ok := r.files[filePath]
+			if !ok {
+				continue
+			}
 
-	var contents contentJson
-	if err := json.Unmarshal(decryptedContents, &contents); err != nil {
-		return err
-	}
+			decryptedFileBytes, err := decryptCfb(fileBytes, []byte(content.Key))
+			if err != nil {
+				return fmt.Errorf("failed to decrypt %s file with key %s: %w", filePath, content.Key, err)
+			}
+			if !plausibleContent(filePath, decryptedFileBytes) {
+				decryptErr.Files = append(decryptErr.Files, &FileError{Path: filePath, Err: ErrImplausibleContent})
+			}
 
-	for _, content := range contents.Content {
-		if content.Key == "" {
-			continue
-		}
-		fileBytes, ok := r.files[content.Path]
-		if !ok {
-			continue
+			r.files[filePath] = decryptedFileBytes
 		}
+		delete(r.files, scope.prefix+"contents.json")
+	}
+	r.encrypted = false
 
-		decryptedFileBytes, err := decryptCfb(fileBytes, []byte(content.Key))
-		if err != nil {
-			return fmt.Errorf("failed to decrypt %s file with key %s: %w", content.Path, content.Key, err)
+	for fileName := range r.files {
+		if _, ok := listed[fileName]; !ok && !strings.HasSuffix(fileName, "/") {
+			decryptErr.Unlisted = append(decryptErr.Unlisted, fileName)
 		}
-
-		r.files[content.Path] = decryptedFileBytes
 	}
-
-	delete(r.files, "contents.json")
-	r.encrypted = false
-	return nil
+	if len(decryptErr.Files) == 0 && len(decryptErr.Unlisted) == 0 {
+		return nil
+	}
+	sort.Slice(decryptErr.Files, func(i, j int) bool {
+		return decryptErr.Files[i].Path < decryptErr.Files[j].Path
+	})
+	sort.Strings(decryptErr.Unlisted)
+	return decryptErr
 }
 
 func (r *ResourcePack) CompressPNGFiles() error {
@@ -260,54 +362,106 @@ func (r *ResourcePack) MinifyJSONFiles() error {
 	return nil
 }
 
+// Encrypt encrypts every file of the pack except manifest.json and pack_icon.png with the key given.
 func (r *ResourcePack) Encrypt(key []byte) error {
+	return r.EncryptWithOptions(key, EncryptOptions{})
+}
+
+// EncryptWithOptions encrypts the pack with the key given, using opts to select the files to encrypt. Every
+// subpack declared in the manifest gets its own contents.json, encrypted with the same key.
+func (r *ResourcePack) EncryptWithOptions(key []byte, opts EncryptOptions) error {
 	if r.encrypted {
 		return errors.New("unable to encrypt pack that already encrypted before")
 	}
 
-	contents := make([]contentJsonEntry, 0)
+	for _, scope := range r.contentsScopes() {
+		contents := make([]contentJsonEntry, 0)
+
+		for fileName, decryptedFileBytes := range r.files {
+			if !scope.contains(fileName) {
+				continue
+			}
+			relativeName := strings.TrimPrefix(fileName, scope.prefix)
+			if relativeName == "" {
+				continue
+			}
+			if !opts.encrypts(fileName) {
+				contents = append(contents, contentJsonEntry{
+					Path: relativeName,
+				})
+				continue
+			}
+
+			fileKey := opts.fileKey(fileName)
+			encryptedFileBytes, err := encryptCfb(decryptedFileBytes, fileKey)
+			if err != nil {
+				return err
+			}
+			r.files[fileName] = encryptedFileBytes
 
-	for fileName, decryptedFileBytes := range r.files {
-		if fileName == "manifest.json" || fileName == "pack_icon.png" {
 			contents = append(contents, contentJsonEntry{
-				Path: fileName,
+				Path: relativeName,
+				Key:  string(fileKey),
 			})
-			continue
 		}
 
-		fileKey := GenerateKey()
-		encryptedFileBytes, err := encryptCfb(decryptedFileBytes, fileKey)
-		if err != nil {
+		if err := r.writeContents(scope, contents, key); err != nil {
 			return err
 		}
-		r.files[fileName] = encryptedFileBytes
-
-		contents = append(contents, contentJsonEntry{
-			Path: fileName,
-			Key:  string(fileKey),
-		})
 	}
+	r.encrypted = true
+	return nil
+}
 
-	contentBytes, err := json.Marshal(contentJson{Content: contents})
-	if err != nil {
-		return err
+// Rekey re-encrypts every contents.json of an encrypted pack with newKey, after decrypting them with oldKey.
+// If rotateFileKeys is true, every encrypted file also gets a new key. File contents and the UUID of the pack
+// are left untouched, and no decrypted data leaves memory.
+func (r *ResourcePack) Rekey(oldKey, newKey []byte, rotateFileKeys bool) error {
+	if !r.encrypted {
+		return ErrNotEncrypted
+	}
+	if _, err := newPackStream(newKey, false); err != nil {
+		return fmt.Errorf("invalid new key: %w", err)
 	}
 
-	contentBytes2 := bytes.NewBuffer(nil)
-	contentBytes2.Write(make([]byte, 4))          // version
-	contentBytes2.WriteString("\xfc\xb9\xcf\x9b") // type
-	contentBytes2.Write(make([]byte, 8))          // padding
-	contentBytes2.WriteString("\x24")             // separator
-	contentBytes2.WriteString(r.uuid)             // uuid
-	contentBytes2.Write(make([]byte, 256-contentBytes2.Len()))
-
-	encryptedContentBytes, err := encryptCfb(contentBytes, key)
-	if err != nil {
-		return err
+	scopes := r.contentsScopes()
+	contents := make([][]contentJsonEntry, len(scopes))
+	for i, scope := range scopes {
+		if _, ok := r.files[scope.prefix+"contents.json"]; !ok && scope.prefix != "" {
+			continue
+		}
+		entries, err := r.readContents(scope, oldKey)
+		if err != nil {
+			return err
+		}
+		contents[i] = entries
 	}
-	contentBytes2.Write(encryptedContentBytes)
 
-	r.files["contents.json"] = contentBytes2.Bytes()
+	for i, scope := range scopes {
+		if contents[i] == nil {
+			continue
+		}
+		if rotateFileKeys {
+			for j, content := range contents[i] {
+				filePath := scope.prefix + content.Path
+				fileBytes, ok := r.files[filePath]
+				if content.Key == "" || !ok {
+					continue
+				}
+				if _, err := decryptCfb(fileBytes, []byte(content.Key)); err != nil {
+					return fmt.Errorf("failed to decrypt %s file with key %s: %w", filePath, content.Key, err)
+				}
+				fileKey := GenerateKey()
+				if _, err := encryptCfb(fileBytes, fileKey); err != nil {
+					return err
+				}
+				contents[i][j].Key = string(fileKey)
+			}
+		}
+		if err := r.writeContents(scope, contents[i], newKey); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -315,11 +469,7 @@ func (r *ResourcePack) ComputeHash() []byte {
 	toHash := bytes.Buffer{}
 	fileLen := make([]byte, 4)
 
-	fileNames := make([]string, 0, len(r.files))
-	for fileName := range r.files {
-		fileNames = append(fileNames, fileName)
-	}
-	sort.Strings(fileNames)
+	fileNames := r.FileNames()
 
 	binary.BigEndian.PutUint32(fileLen, uint32(len(fileNames)))
 	toHash.Write(fileLen)
@@ -342,14 +492,15 @@ func (r *ResourcePack) ComputeHash() []byte {
 }
 
 func (r *ResourcePack) Save(path string) error {
-	zipFile, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0777)
+	zipFile, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_RDWR, 0777)
 	if err != nil {
 		return err
 	}
 	defer zipFile.Close()
 	arc := zip.NewWriter(zipFile)
 
-	for fileName, fileBytes := range r.files {
+	for _, fileName := range r.FileNames() {
+		fileBytes := r.files[fileName]
 		w, err := arc.Create(fileName)
 		if err != nil {
 			return err
@@ -362,12 +513,36 @@ func (r *ResourcePack) Save(path string) error {
 	return arc.Close()
 }
 
+// SaveToDir writes the files of the pack to the directory at the path given, creating it if needed.
+func (r *ResourcePack) SaveToDir(path string) error {
+	for _, fileName := range r.FileNames() {
+		if !fs.ValidPath(strings.TrimSuffix(fileName, "/")) {
+			return fmt.Errorf("invalid file name %s", fileName)
+		}
+		filePath := filepath.Join(path, filepath.FromSlash(fileName))
+		if strings.HasSuffix(fileName, "/") {
+			if err := os.MkdirAll(filePath, 0777); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(filePath), 0777); err != nil {
+			return err
+		}
+		if err := os.WriteFile(filePath, r.files[fileName], 0777); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // SaveToBytes returns the zip file as a byte slice without creating a file
 func (r *ResourcePack) SaveToBytes() ([]byte, error) {
 	var buf bytes.Buffer
 	arc := zip.NewWriter(&buf)
 
-	for fileName, fileBytes := range r.files {
+	for _, fileName := range r.FileNames() {
+		fileBytes := r.files[fileName]
 		w, err := arc.Create(fileName)
 		if err != nil {
 			return nil, err
@@ -384,6 +559,8 @@ func (r *ResourcePack) SaveToBytes() ([]byte, error) {
 	return buf.Bytes(), nil
 }
 
+// RegenerateUUID sets the header and module UUIDs of the pack to UUIDs derived from the seed given, or to
+// random UUIDs if the seed is nil.
 func (r *ResourcePack) RegenerateUUID(seed []byte) error {
 	if seed == nil {
 		seed = make([]byte, 16)
@@ -397,51 +574,7 @@ func (r *ResourcePack) RegenerateUUID(seed []byte) error {
 		seed = append(seed, make([]byte, 16-len(seed))...)
 	}
 
-	manifestBytes, err := r.loadFile("manifest.json")
-	if err != nil {
-		return err
-	}
-
-	var manifest map[string]any
-	if err := json.Unmarshal(manifestBytes, &manifest); err != nil {
-		return err
-	}
-
-	mod := 0
-	newPackUuid := uuidFromSeed(seed, mod)
-	mod++
-
-	if _, ok := manifest["header"]; !ok {
-		return errors.New("manifest.json header not found")
-	}
-
-	if _, ok := manifest["header"].(map[string]any); !ok {
-		return errors.New("manifest.json header is not a map[string]any")
-	}
-
-	manifest["header"].(map[string]any)["uuid"] = newPackUuid
-
-	modules, ok := manifest["modules"]
-	if ok {
-		modules2, ok := modules.([]any)
-		if !ok {
-			return errors.New("manifest.json modules is not a []any")
-		}
-		for _, module := range modules2 {
-			if _, ok := module.(map[string]any); !ok {
-				return errors.New("manifest.json module is not a map[string]any")
-			}
-			module.(map[string]any)["uuid"] = uuidFromSeed(seed, mod)
-			mod++
-		}
-	}
-
-	manifestBytes2, err := json.Marshal(manifest)
-	if err != nil {
-		return err
-	}
-
-	r.uuid = newPackUuid
-	r.files["manifest.json"] = manifestBytes2
-	return nil
+	return r.setUUIDs(func(i int) string {
+		return uuidFromSeed(seed, i)
+	})
 }
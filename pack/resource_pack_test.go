@@ -0,0 +1,319 @@
+package pack
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"github.com/google/uuid"
+	"strings"
+	"testing"
+)
+
+const testManifest = `{"format_version":2,"header":{"name":"test","description":"","uuid":"6f0b5b1e-4c5c-4a4e-9d59-2f8a3b2f6e11","version":[1,0,0],"min_engine_version":[1,20,0]},"modules":[{"type":"resources","uuid":"8a3a7a0c-3d1b-4b8f-8a8e-6c1f4e0e2b22","version":[1,0,0]}]}`
+
+// newTestPack builds a resource pack in memory from the files given. A manifest is added if none is present.
+func newTestPack(t testing.TB, files map[string][]byte) *ResourcePack {
+	t.Helper()
+	if _, ok := files["manifest.json"]; !ok {
+		files["manifest.json"] = []byte(testManifest)
+	}
+	var buf bytes.Buffer
+	arc := zip.NewWriter(&buf)
+	for fileName, fileBytes := range files {
+		w, err := arc.Create(fileName)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write(fileBytes); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := arc.Close(); err != nil {
+		t.Fatal(err)
+	}
+	rp, err := LoadResourcePackFromBytes(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rp
+}
+
+func testPackFiles() map[string][]byte {
+	return map[string][]byte{
+		"textures/blocks/stone.png": append(bytes.Clone(pngMagic), 1, 2, 3, 4),
+		"models/entity/test.json":   []byte(`{"format_version":"1.12.0"}`),
+		"texts/en_US.lang":          []byte("pack.name=Test"),
+	}
+}
+
+func TestDecryptWrongKey(t *testing.T) {
+	rp := newTestPack(t, testPackFiles())
+	if err := rp.Encrypt([]byte("0123Z5678K0123u567890123Z56789P1")); err != nil {
+		t.Fatal(err)
+	}
+	if err := rp.Decrypt([]byte("aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa")); !errors.Is(err, ErrWrongKey) {
+		t.Fatalf("expected ErrWrongKey, got %v", err)
+	}
+	if err := rp.Decrypt([]byte("0123Z5678K0123u567890123Z56789P1")); err != nil {
+		t.Fatalf("decrypt after wrong key attempt: %v", err)
+	}
+	if err := rp.Decrypt([]byte("0123Z5678K0123u567890123Z56789P1")); !errors.Is(err, ErrNotEncrypted) {
+		t.Fatalf("expected ErrNotEncrypted, got %v", err)
+	}
+}
+
+func TestDecryptReportsBadFiles(t *testing.T) {
+	key := []byte("0123Z5678K0123u567890123Z56789P1")
+	rp := newTestPack(t, testPackFiles())
+	if err := rp.Encrypt(key); err != nil {
+		t.Fatal(err)
+	}
+	rp.files["models/entity/test.json"][0] ^= 0xff
+	rp.files["unlisted.txt"] = []byte("hello")
+
+	var decryptErr *DecryptError
+	if err := rp.Decrypt(key); !errors.As(err, &decryptErr) {
+		t.Fatalf("expected *DecryptError, got %v", err)
+	}
+	if len(decryptErr.Files) != 1 || decryptErr.Files[0].Path != "models/entity/test.json" {
+		t.Fatalf("unexpected file errors: %v", decryptErr.Files)
+	}
+	if !errors.Is(decryptErr, ErrImplausibleContent) {
+		t.Fatal("expected ErrImplausibleContent")
+	}
+	if len(decryptErr.Unlisted) != 1 || decryptErr.Unlisted[0] != "unlisted.txt" {
+		t.Fatalf("unexpected unlisted files: %v", decryptErr.Unlisted)
+	}
+}
+
+func TestEncryptWithOptions(t *testing.T) {
+	files := testPackFiles()
+	rp := newTestPack(t, testPackFiles())
+	opts := EncryptOptions{Exclude: []string{"texts/", "textures/**/stone.png"}}
+	if err := rp.EncryptWithOptions([]byte("0123Z5678K0123u567890123Z56789P1"), opts); err != nil {
+		t.Fatal(err)
+	}
+	for fileName, fileBytes := range files {
+		encrypted := !bytes.Equal(rp.files[fileName], fileBytes)
+		if want := fileName == "models/entity/test.json"; encrypted != want {
+			t.Errorf("%s: encrypted = %v, want %v", fileName, encrypted, want)
+		}
+	}
+	if err := rp.Decrypt([]byte("0123Z5678K0123u567890123Z56789P1")); err != nil {
+		t.Fatalf("excluded files must be listed in contents.json: %v", err)
+	}
+}
+
+func TestEncryptDecryptSubpacks(t *testing.T) {
+	key := []byte("0123Z5678K0123u567890123Z56789P1")
+	files := testPackFiles()
+	files["manifest.json"] = []byte(`{"format_version":2,"header":{"name":"test","uuid":"6f0b5b1e-4c5c-4a4e-9d59-2f8a3b2f6e11","version":[1,0,0]},"modules":[],"subpacks":[{"folder_name":"low","name":"Low","memory_tier":0}]}`)
+	files["subpacks/low/textures/blocks/stone.png"] = append(bytes.Clone(pngMagic), 5, 6, 7)
+	rp := newTestPack(t, files)
+	if err := rp.Encrypt(key); err != nil {
+		t.Fatal(err)
+	}
+
+	subpackContents, err := rp.readContents(contentsScope{prefix: "subpacks/low/"}, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(subpackContents) != 1 || subpackContents[0].Path != "textures/blocks/stone.png" || subpackContents[0].Key == "" {
+		t.Fatalf("unexpected subpack contents: %v", subpackContents)
+	}
+	rootContents, err := rp.readContents(contentsScope{}, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range rootContents {
+		if strings.HasPrefix(entry.Path, "subpacks/") {
+			t.Fatalf("subpack file %s listed in root contents.json", entry.Path)
+		}
+	}
+
+	if err := rp.Decrypt(key); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(rp.files["subpacks/low/textures/blocks/stone.png"], files["subpacks/low/textures/blocks/stone.png"]) {
+		t.Fatal("subpack file was not decrypted")
+	}
+	if _, ok := rp.files["subpacks/low/contents.json"]; ok {
+		t.Fatal("subpack contents.json was not removed")
+	}
+}
+
+func TestRekey(t *testing.T) {
+	oldKey := []byte("0123Z5678K0123u567890123Z56789P1")
+	newKey := []byte("abcdefghijklmnopqrstuvwxyz012345")
+	files := testPackFiles()
+	rp := newTestPack(t, testPackFiles())
+	if err := rp.Encrypt(oldKey); err != nil {
+		t.Fatal(err)
+	}
+	uuid := rp.UUID()
+	if err := rp.Rekey(newKey, newKey, false); !errors.Is(err, ErrWrongKey) {
+		t.Fatalf("expected ErrWrongKey, got %v", err)
+	}
+	if err := rp.Rekey(oldKey, newKey, true); err != nil {
+		t.Fatal(err)
+	}
+	if err := rp.Decrypt(oldKey); !errors.Is(err, ErrWrongKey) {
+		t.Fatalf("expected ErrWrongKey for old key, got %v", err)
+	}
+	if err := rp.Decrypt(newKey); err != nil {
+		t.Fatal(err)
+	}
+	if rp.UUID() != uuid {
+		t.Fatal("uuid changed")
+	}
+	for fileName, fileBytes := range files {
+		if !bytes.Equal(rp.files[fileName], fileBytes) {
+			t.Errorf("%s: content changed", fileName)
+		}
+	}
+}
+
+func TestRegenerateUUIDEncrypted(t *testing.T) {
+	key := []byte("0123Z5678K0123u567890123Z56789P1")
+	rp := newTestPack(t, testPackFiles())
+	if err := rp.Encrypt(key); err != nil {
+		t.Fatal(err)
+	}
+	if err := rp.RegenerateUUIDFromName(uuid.NameSpaceDNS, "example.com"); err != nil {
+		t.Fatal(err)
+	}
+	expected := uuid.NewSHA1(uuid.NameSpaceDNS, []byte("example.com")).String()
+	if rp.UUID() != expected {
+		t.Fatalf("got uuid %s, want %s", rp.UUID(), expected)
+	}
+	if headerUUID, err := parseContentsHeader(rp.files["contents.json"]); err != nil || headerUUID != expected {
+		t.Fatalf("contents.json header uuid %s (%v), want %s", headerUUID, err, expected)
+	}
+	manifest, err := rp.Manifest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if manifest.Modules[0].UUID == expected || manifest.Modules[0].UUID == "8a3a7a0c-3d1b-4b8f-8a8e-6c1f4e0e2b22" {
+		t.Errorf("module uuid not regenerated: %s", manifest.Modules[0].UUID)
+	}
+	if err := rp.Decrypt(key); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	key := []byte("0123Z5678K0123u567890123Z56789P1")
+	rp := newTestPack(t, testPackFiles())
+	if err := rp.Encrypt(key); err != nil {
+		t.Fatal(err)
+	}
+	if err := rp.Verify(key); err != nil {
+		t.Fatal(err)
+	}
+	if err := rp.Verify([]byte("aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa")); !errors.Is(err, ErrWrongKey) {
+		t.Fatalf("expected ErrWrongKey, got %v", err)
+	}
+
+	header := bytes.Clone(rp.files["contents.json"])
+	rp.files["contents.json"][17] ^= 0xff
+	err := rp.Verify(key)
+	if joined, ok := err.(interface{ Unwrap() []error }); !ok || len(joined.Unwrap()) != 1 || !errors.Is(err, ErrInvalidHeader) {
+		t.Fatalf("expected one ErrInvalidHeader for a header uuid mismatch, got %v", err)
+	}
+	rp.files["contents.json"] = header
+
+	rp.files["unlisted.txt"] = []byte("hello")
+	rp.files["textures/blocks/stone.png"][0] ^= 0xff
+	err = rp.Verify(key)
+	if !errors.Is(err, ErrUnlisted) || !errors.Is(err, ErrImplausibleContent) {
+		t.Fatalf("expected ErrUnlisted and ErrImplausibleContent, got %v", err)
+	}
+}
+
+func TestEncryptSeedReproducible(t *testing.T) {
+	key := []byte("0123Z5678K0123u567890123Z56789P1")
+	build := func(seed []byte) []byte {
+		rp := newTestPack(t, testPackFiles())
+		if err := rp.EncryptWithOptions(key, EncryptOptions{Seed: seed}); err != nil {
+			t.Fatal(err)
+		}
+		b, err := rp.SaveToBytes()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+	if !bytes.Equal(build([]byte("seed")), build([]byte("seed"))) {
+		t.Fatal("encryption with equal seeds is not reproducible")
+	}
+	if bytes.Equal(build([]byte("seed")), build([]byte("other seed"))) {
+		t.Fatal("encryption with different seeds is equal")
+	}
+	if bytes.Equal(build(nil), build(nil)) {
+		t.Fatal("encryption without seed is reproducible")
+	}
+}
+
+func TestAddRemoveFileEncrypted(t *testing.T) {
+	key := []byte("0123Z5678K0123u567890123Z56789P1")
+	rp := newTestPack(t, testPackFiles())
+	if err := rp.Encrypt(key); err != nil {
+		t.Fatal(err)
+	}
+	if err := rp.AddFile("texts/de_DE.lang", []byte("pack.name=Test"), nil); !errors.Is(err, ErrKeyRequired) {
+		t.Fatalf("expected ErrKeyRequired, got %v", err)
+	}
+	for _, name := range []string{"", ".", "../x", "/abs", "texts/../x", "texts//x"} {
+		if err := rp.AddFile(name, []byte("x"), key); err == nil {
+			t.Fatalf("expected error adding file %q", name)
+		}
+	}
+	content := []byte("pack.name=Test")
+	if err := rp.AddFile("texts/de_DE.lang", content, key); err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "pack.name=Test" {
+		t.Fatal("content passed to AddFile was modified")
+	}
+	if err := rp.RemoveFile("models/entity/test.json", key); err != nil {
+		t.Fatal(err)
+	}
+	if err := rp.Verify(key); err != nil {
+		t.Fatal(err)
+	}
+	if err := rp.Decrypt(key); err != nil {
+		t.Fatal(err)
+	}
+	if string(rp.files["texts/de_DE.lang"]) != "pack.name=Test" {
+		t.Fatal("added file was not decrypted")
+	}
+	if _, ok := rp.files["models/entity/test.json"]; ok {
+		t.Fatal("removed file is still in the pack")
+	}
+}
+
+func TestAddFileKeepsUnencryptedFile(t *testing.T) {
+	key := []byte("0123Z5678K0123u567890123Z56789P1")
+	rp := newTestPack(t, testPackFiles())
+	if err := rp.EncryptWithOptions(key, EncryptOptions{Exclude: []string{"texts/*"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := rp.AddFile("texts/en_US.lang", []byte("pack.name=Replaced"), key); err != nil {
+		t.Fatal(err)
+	}
+	if string(rp.files["texts/en_US.lang"]) != "pack.name=Replaced" {
+		t.Fatal("replaced file listed without a key was encrypted")
+	}
+	entries, err := rp.readContents(rp.contentsScopes()[0], key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		if entry.Path == "texts/en_US.lang" && entry.Key != "" {
+			t.Fatal("replaced file listed with a key")
+		}
+	}
+	if err := rp.Verify(key); err != nil {
+		t.Fatal(err)
+	}
+}
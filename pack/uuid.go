@@ -0,0 +1,57 @@
+package pack
+
+import (
+	"errors"
+	"github.com/google/uuid"
+	"strconv"
+)
+
+// UUIDs returns the header UUID of the manifest followed by the UUID of every module.
+func (m Manifest) UUIDs() []string {
+	uuids := []string{m.Header.UUID}
+	for _, module := range m.Modules {
+		uuids = append(uuids, module.UUID)
+	}
+	return uuids
+}
+
+// RegenerateUUIDFromName sets the header UUID of the pack to the name-based (version 5) UUID of the name in
+// the namespace given. Module UUIDs are derived from the header UUID in the same way, so that the UUIDs of the
+// pack are the same every time for the same name.
+func (r *ResourcePack) RegenerateUUIDFromName(namespace uuid.UUID, name string) error {
+	header := uuid.NewSHA1(namespace, []byte(name))
+	return r.setUUIDs(func(i int) string {
+		if i == 0 {
+			return header.String()
+		}
+		return uuid.NewSHA1(header, []byte("module "+strconv.Itoa(i-1))).String()
+	})
+}
+
+// setUUIDs sets the header UUID of the manifest to uuidFor(0) and the UUID of the nth module to uuidFor(n+1).
+// If the pack is encrypted, the UUID in the header of its contents.json files is updated as well.
+func (r *ResourcePack) setUUIDs(uuidFor func(i int) string) error {
+	header := uuidFor(0)
+	err := r.updateManifest(func(manifest map[string]any) error {
+		h, ok := manifest["header"].(map[string]any)
+		if !ok {
+			return errors.New("manifest.json header not found")
+		}
+		h["uuid"] = header
+
+		modules, _ := manifest["modules"].([]any)
+		for i, m := range modules {
+			module, ok := m.(map[string]any)
+			if !ok {
+				return errors.New("manifest.json module is not an object")
+			}
+			module["uuid"] = uuidFor(i + 1)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	r.uuid = header
+	return r.rewriteContentsUUID()
+}
@@ -0,0 +1,98 @@
+package pack
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/sandertv/gophertunnel/minecraft/resource"
+	"strings"
+)
+
+// Verify checks that an encrypted pack is consistent and can be loaded by the client with the key given. It
+// checks the header and body of every contents.json, that every listed file exists and decrypts to plausible
+// content, that manifest.json and pack_icon.png are not encrypted, that no file is missing from contents.json
+// and that the pack can be read by gophertunnel. The pack is not modified. All problems found are returned
+// joined into one error, each as a *FileError where it concerns a single file.
+func (r *ResourcePack) Verify(key []byte) error {
+	if !r.encrypted {
+		return ErrNotEncrypted
+	}
+
+	var problems []error
+	listed := map[string]struct{}{}
+	for _, scope := range r.contentsScopes() {
+		contentsPath := scope.prefix + "contents.json"
+		contentsBytes, ok := r.files[contentsPath]
+		if !ok {
+			if scope.prefix == "" {
+				return fmt.Errorf("contents.json not found")
+			}
+			problems = append(problems, &FileError{Path: contentsPath, Err: errors.New("subpack contents.json not found")})
+			continue
+		}
+		listed[contentsPath] = struct{}{}
+		headerUUID, err := parseContentsHeader(contentsBytes)
+		if err != nil {
+			problems = append(problems, &FileError{Path: contentsPath, Err: err})
+			continue
+		}
+		if headerUUID != r.uuid {
+			problems = append(problems, &FileError{Path: contentsPath, Err: fmt.Errorf("%w: uuid %s does not match manifest uuid %s", ErrInvalidHeader, headerUUID, r.uuid)})
+		}
+		contents, err := r.readContents(scope, key)
+		if err != nil {
+			problems = append(problems, &FileError{Path: contentsPath, Err: err})
+			continue
+		}
+
+		for _, content := range contents {
+			filePath := scope.prefix + content.Path
+			listed[filePath] = struct{}{}
+			fileBytes, ok := r.files[filePath]
+			if !ok {
+				problems = append(problems, &FileError{Path: filePath, Err: errors.New("listed in contents.json but not found")})
+				continue
+			}
+			if content.Key == "" {
+				continue
+			}
+			if filePath == "manifest.json" || filePath == "pack_icon.png" {
+				problems = append(problems, &FileError{Path: filePath, Err: errors.New("must not be encrypted")})
+				continue
+			}
+			decryptedFileBytes, err := decryptCfb(bytes.Clone(fileBytes), []byte(content.Key))
+			if err != nil {
+				problems = append(problems, &FileError{Path: filePath, Err: err})
+				continue
+			}
+			if !plausibleContent(filePath, decryptedFileBytes) {
+				problems = append(problems, &FileError{Path: filePath, Err: ErrImplausibleContent})
+			}
+		}
+	}
+
+	for _, fileName := range r.FileNames() {
+		if _, ok := listed[fileName]; !ok && !strings.HasSuffix(fileName, "/") {
+			problems = append(problems, &FileError{Path: fileName, Err: ErrUnlisted})
+		}
+	}
+	if !json.Valid(r.files["manifest.json"]) {
+		problems = append(problems, &FileError{Path: "manifest.json", Err: ErrImplausibleContent})
+	}
+	if icon, ok := r.files["pack_icon.png"]; ok && !plausibleContent("pack_icon.png", icon) {
+		problems = append(problems, &FileError{Path: "pack_icon.png", Err: ErrImplausibleContent})
+	}
+
+	packBytes, err := r.SaveToBytes()
+	if err != nil {
+		return err
+	}
+	compiled, err := resource.Read(bytes.NewReader(packBytes))
+	if err != nil {
+		problems = append(problems, fmt.Errorf("gophertunnel failed to read pack: %w", err))
+	} else if compiled.UUID().String() != r.uuid {
+		problems = append(problems, fmt.Errorf("gophertunnel read uuid %s, expected %s", compiled.UUID(), r.uuid))
+	}
+	return errors.Join(problems...)
+}
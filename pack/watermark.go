@@ -0,0 +1,299 @@
+package pack
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// watermarkSize is the size of the fingerprint that identifies the recipient of a watermarked build.
+const watermarkSize = 8
+
+// watermarkOffset is the offset of the fingerprint in the padding of the contents.json header.
+const watermarkOffset = contentsHeaderSize - 16
+
+// ErrNoWatermark is returned by ResourcePack.Trace when no watermark of any of the recipients was found.
+var ErrNoWatermark = errors.New("no watermark found")
+
+// RecipientBuild is a build of a pack for a single recipient, returned by ResourcePack.BuildForRecipients.
+type RecipientBuild struct {
+	Recipient string
+	Key       []byte
+	Pack      *ResourcePack
+}
+
+// BuildForRecipients builds an encrypted copy of the pack for every recipient given. Each build has a distinct
+// content key and UUID, and carries a watermark identifying the recipient that may be recovered using
+// ResourcePack.Trace. The pack itself is left untouched and must not be encrypted.
+func (r *ResourcePack) BuildForRecipients(recipients []string, opts EncryptOptions) ([]RecipientBuild, error) {
+	if r.encrypted {
+		return nil, errors.New("pack is encrypted")
+	}
+	builds := make([]RecipientBuild, 0, len(recipients))
+	for _, recipient := range recipients {
+		build := r.Clone()
+		if err := build.RegenerateUUID(nil); err != nil {
+			return nil, err
+		}
+		if err := build.Watermark(recipient); err != nil {
+			return nil, fmt.Errorf("watermark pack for %s: %w", recipient, err)
+		}
+		key := GenerateKey()
+		buildOpts := opts
+		if opts.Seed != nil {
+			// Builds for different recipients must not share the keys of their files.
+			buildOpts.Seed = sha256(append(append(bytes.Clone(opts.Seed), 0), recipient...))
+		}
+		if err := build.EncryptWithOptions(key, buildOpts); err != nil {
+			return nil, fmt.Errorf("encrypt pack for %s: %w", recipient, err)
+		}
+		builds = append(builds, RecipientBuild{Recipient: recipient, Key: key, Pack: build})
+	}
+	return builds, nil
+}
+
+// Watermark embeds a fingerprint of the recipient given in the pack. The fingerprint is encoded in the order
+// of the keys of JSON objects, which survives decryption of the pack, and is written to the padding of the
+// contents.json header when the pack is encrypted. As the game reads the keys of some files in order, such as
+// the controls of UI definitions, only JSON files of the kinds in watermarkDirs and watermarkFiles are changed.
+// These are minified as a side effect.
+func (r *ResourcePack) Watermark(recipient string) error {
+	if r.encrypted {
+		return errors.New("pack is encrypted")
+	}
+	fingerprint := watermarkFingerprint(recipient)
+	for fileName, fileBytes := range r.files {
+		if !watermarkable(fileName) {
+			continue
+		}
+		fileBytes = jsonReplaceRegex1.ReplaceAll(fileBytes, []byte(""))
+		fileBytes = jsonReplaceRegex2.ReplaceAll(fileBytes, []byte(""))
+		dec := json.NewDecoder(bytes.NewReader(fileBytes))
+		dec.UseNumber()
+		var data any
+		if err := dec.Decode(&data); err != nil {
+			// Files that are not valid JSON are left as they are, like MinifyJSONFiles does.
+			continue
+		}
+		var buf bytes.Buffer
+		if err := writeWatermarkedJSON(&buf, data, fileName, "", fingerprint); err != nil {
+			return fmt.Errorf("%s: %w", fileName, err)
+		}
+		r.files[fileName] = buf.Bytes()
+	}
+	r.watermark = fingerprint
+	return nil
+}
+
+// TraceResult is the result of ResourcePack.Trace.
+type TraceResult struct {
+	// Recipient is the recipient whose watermark was found.
+	Recipient string
+	// Source is where the watermark was found, either "contents.json" or "json".
+	Source string
+	// Confidence is the fraction of watermark bits found in JSON files that matched the recipient.
+	Confidence float64
+}
+
+// Trace finds which of the recipients given the pack was built for by looking for their watermark. The
+// contents.json header is checked first and does not require a key. If it does not hold a watermark, the key is
+// used to decrypt the pack, after which the order of keys in JSON objects is checked.
+func (r *ResourcePack) Trace(recipients []string, key []byte) (TraceResult, error) {
+	target := r
+	if r.encrypted {
+		if contentsBytes, ok := r.files["contents.json"]; ok && len(contentsBytes) >= contentsHeaderSize {
+			found := contentsBytes[watermarkOffset : watermarkOffset+watermarkSize]
+			for _, recipient := range recipients {
+				if bytes.Equal(found, watermarkFingerprint(recipient)) {
+					return TraceResult{Recipient: recipient, Source: "contents.json", Confidence: 1}, nil
+				}
+			}
+		}
+		if len(key) == 0 {
+			return TraceResult{}, ErrNoWatermark
+		}
+		target = r.Clone()
+		var decryptErr *DecryptError
+		if err := target.Decrypt(key); err != nil && !errors.As(err, &decryptErr) {
+			return TraceResult{}, err
+		}
+	}
+
+	// Every JSON object with at least two keys votes for one bit of the fingerprint.
+	var votes [watermarkSize * 8][2]int
+	for fileName, fileBytes := range target.files {
+		if !watermarkable(fileName) {
+			continue
+		}
+		_ = readJSONKeyOrders(json.NewDecoder(bytes.NewReader(fileBytes)), "", func(objPath string, keys []string) {
+			switch {
+			case sort.StringsAreSorted(keys):
+				votes[watermarkBit(fileName, objPath)][0]++
+			case sort.IsSorted(sort.Reverse(sort.StringSlice(keys))):
+				votes[watermarkBit(fileName, objPath)][1]++
+			}
+		})
+	}
+
+	total := 0
+	for _, v := range votes {
+		total += v[0] + v[1]
+	}
+	if total == 0 {
+		return TraceResult{}, ErrNoWatermark
+	}
+	best := TraceResult{}
+	for _, recipient := range recipients {
+		fingerprint := watermarkFingerprint(recipient)
+		matched := 0
+		for bit, v := range votes {
+			matched += v[fingerprint[bit/8]>>(bit%8)&1]
+		}
+		if confidence := float64(matched) / float64(total); confidence > best.Confidence {
+			best = TraceResult{Recipient: recipient, Source: "json", Confidence: confidence}
+		}
+	}
+	// Unwatermarked packs have their keys sorted, which matches about half of the bits of any fingerprint.
+	if best.Confidence < 0.9 {
+		return TraceResult{}, ErrNoWatermark
+	}
+	return best, nil
+}
+
+// watermarkDirs are the directories of which JSON files carry the watermark. The game does not depend on the
+// order of the keys in these files, unlike in files such as UI definitions, animations and render controllers.
+var watermarkDirs = []string{"attachables/", "entity/", "models/", "sounds/", "textures/"}
+
+// watermarkFiles are the JSON files at the root of a pack that carry the watermark, for the same reason.
+var watermarkFiles = []string{"blocks.json", "sounds.json"}
+
+// watermarkable reports whether the JSON file with the name given carries the watermark. Files of subpacks are
+// treated like the files of the pack.
+func watermarkable(fileName string) bool {
+	if rest, ok := strings.CutPrefix(fileName, "subpacks/"); ok {
+		if _, name, ok := strings.Cut(rest, "/"); ok {
+			fileName = name
+		}
+	}
+	if !strings.HasSuffix(fileName, ".json") {
+		return false
+	}
+	return slices.Contains(watermarkFiles, fileName) || slices.ContainsFunc(watermarkDirs, func(dir string) bool {
+		return strings.HasPrefix(fileName, dir)
+	})
+}
+
+// contentsWatermark returns the watermark in the header of the contents.json passed, or nil if it has none.
+func contentsWatermark(contentsBytes []byte) []byte {
+	if len(contentsBytes) < contentsHeaderSize {
+		return nil
+	}
+	watermark := contentsBytes[watermarkOffset : watermarkOffset+watermarkSize]
+	if bytes.Count(watermark, []byte{0}) == watermarkSize {
+		return nil
+	}
+	return bytes.Clone(watermark)
+}
+
+// watermarkFingerprint returns the fingerprint of the recipient given.
+func watermarkFingerprint(recipient string) []byte {
+	return sha256([]byte("bedrockpack-watermark:" + recipient))[:watermarkSize]
+}
+
+// watermarkBit returns the bit of the fingerprint that is encoded in the JSON object at the path given.
+func watermarkBit(fileName, objPath string) int {
+	return int(sha256([]byte(fileName + "\x00" + objPath))[0]) % (watermarkSize * 8)
+}
+
+// writeWatermarkedJSON writes v as minified JSON to w. The keys of every object are sorted ascending or
+// descending depending on the bit of the fingerprint selected by the path of the object.
+func writeWatermarkedJSON(w *bytes.Buffer, v any, fileName, objPath string, fingerprint []byte) error {
+	switch v := v.(type) {
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		if bit := watermarkBit(fileName, objPath); len(keys) > 1 && fingerprint[bit/8]>>(bit%8)&1 == 1 {
+			sort.Sort(sort.Reverse(sort.StringSlice(keys)))
+		}
+		w.WriteByte('{')
+		for i, k := range keys {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			keyBytes, _ := json.Marshal(k)
+			w.Write(keyBytes)
+			w.WriteByte(':')
+			if err := writeWatermarkedJSON(w, v[k], fileName, objPath+"/"+k, fingerprint); err != nil {
+				return err
+			}
+		}
+		w.WriteByte('}')
+	case []any:
+		w.WriteByte('[')
+		for i, elem := range v {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			if err := writeWatermarkedJSON(w, elem, fileName, objPath+"/"+strconv.Itoa(i), fingerprint); err != nil {
+				return err
+			}
+		}
+		w.WriteByte(']')
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		w.Write(b)
+	}
+	return nil
+}
+
+// readJSONKeyOrders reads a JSON value from dec and calls f with the keys of every object in the order in
+// which they appear.
+func readJSONKeyOrders(dec *json.Decoder, objPath string, f func(objPath string, keys []string)) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	switch tok {
+	case json.Delim('{'):
+		var keys []string
+		for dec.More() {
+			keyTok, err := dec.Token()
+			if err != nil {
+				return err
+			}
+			key, _ := keyTok.(string)
+			keys = append(keys, key)
+			if err := readJSONKeyOrders(dec, objPath+"/"+key, f); err != nil {
+				return err
+			}
+		}
+		if _, err := dec.Token(); err != nil && err != io.EOF {
+			return err
+		}
+		if len(keys) > 1 {
+			f(objPath, keys)
+		}
+	case json.Delim('['):
+		for i := 0; dec.More(); i++ {
+			if err := readJSONKeyOrders(dec, objPath+"/"+strconv.Itoa(i), f); err != nil {
+				return err
+			}
+		}
+		if _, err := dec.Token(); err != nil && err != io.EOF {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package pack
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBuildForRecipientsTrace(t *testing.T) {
+	files := testPackFiles()
+	var objects []string
+	for i := 0; i < 64; i++ {
+		objects = append(objects, fmt.Sprintf(`"bone_%d":{"name":"bone_%d","pivot":[0,%d,0],"rotation":[0,0,0]}`, i, i, i))
+	}
+	files["models/entity/bones.json"] = []byte("{" + strings.Join(objects, ",") + "}")
+	rp := newTestPack(t, files)
+
+	recipients := []string{"alpha.example.com", "beta.example.com", "gamma.example.com"}
+	builds, err := rp.BuildForRecipients(recipients, EncryptOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rp.Encrypted() {
+		t.Fatal("original pack was modified")
+	}
+
+	for i, build := range builds {
+		for _, other := range builds[i+1:] {
+			if build.Pack.UUID() == other.Pack.UUID() || string(build.Key) == string(other.Key) {
+				t.Fatal("builds share a uuid or key")
+			}
+		}
+
+		res, err := build.Pack.Trace(recipients, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Recipient != build.Recipient || res.Source != "contents.json" {
+			t.Fatalf("traced %+v, want %s from contents.json", res, build.Recipient)
+		}
+
+		// The JSON watermark must survive decryption of the pack.
+		if err := build.Pack.Decrypt(build.Key); err != nil {
+			t.Fatal(err)
+		}
+		res, err = build.Pack.Trace(recipients, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Recipient != build.Recipient || res.Source != "json" {
+			t.Fatalf("traced %+v, want %s from json", res, build.Recipient)
+		}
+	}
+
+	if _, err := rp.Trace(recipients, nil); !errors.Is(err, ErrNoWatermark) {
+		t.Fatalf("expected ErrNoWatermark for unwatermarked pack, got %v", err)
+	}
+}
+
+func TestWatermarkSurvivesEdits(t *testing.T) {
+	rp := newTestPack(t, testPackFiles())
+	recipients := []string{"alpha.example.com", "beta.example.com"}
+	opts := EncryptOptions{Seed: []byte("seed")}
+	builds, err := rp.BuildForRecipients(recipients, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a, b := opts.fileKey("textures/a.png"), opts.fileKey("textures/a.png"); string(a) != string(b) {
+		t.Fatal("file keys of a seed are not deterministic")
+	}
+	contentsA, _ := builds[0].Pack.readContents(contentsScope{}, builds[0].Key)
+	contentsB, _ := builds[1].Pack.readContents(contentsScope{}, builds[1].Key)
+	for i := range contentsA {
+		if contentsA[i].Key != "" && contentsA[i].Key == contentsB[i].Key {
+			t.Fatalf("builds for different recipients share the key of %s", contentsA[i].Path)
+		}
+	}
+
+	// A build loaded from disk keeps its watermark through edits that rewrite contents.json.
+	build := builds[1]
+	data, err := build.Pack.SaveToBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadResourcePackFromBytes(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newKey := GenerateKey()
+	if err := loaded.Rekey(build.Key, newKey, true); err != nil {
+		t.Fatal(err)
+	}
+	if err := loaded.AddFile("texts/extra.lang", []byte("extra=1"), newKey); err != nil {
+		t.Fatal(err)
+	}
+	if err := loaded.RegenerateUUID(nil); err != nil {
+		t.Fatal(err)
+	}
+	res, err := loaded.Trace(recipients, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Recipient != build.Recipient || res.Source != "contents.json" {
+		t.Fatalf("traced %+v after edits, want %s from contents.json", res, build.Recipient)
+	}
+}
+
+func TestWatermarkKeepsContent(t *testing.T) {
+	files := testPackFiles()
+	files["models/entity/bones.json"] = []byte(`{"format_version":"1.12.0","minecraft:geometry":[{"bones":[{"name":"body","pivot":[0,1,0]}],"description":{"identifier":"geometry.test","texture_width":64}}]}`)
+	files["ui/hud_screen.json"] = []byte(`{"namespace":"hud","root_panel":{"type":"panel","controls":[{"b@common.b":{}},{"a@common.a":{}}]}}`)
+	files["animations/test.json"] = []byte(`{"format_version":"1.8.0","animations":{"animation.test":{"bones":{"body":{"rotation":{"0.0":[0,0,0],"1.0":[0,90,0]}}}}}}`)
+	rp := newTestPack(t, files)
+	original := rp.Clone()
+	if err := rp.Watermark("alpha.example.com"); err != nil {
+		t.Fatal(err)
+	}
+
+	for fileName := range files {
+		if !strings.HasSuffix(fileName, ".json") {
+			continue
+		}
+		if !watermarkable(fileName) {
+			if !bytes.Equal(rp.files[fileName], original.files[fileName]) {
+				t.Errorf("%s: file that is not watermarked was changed", fileName)
+			}
+			continue
+		}
+		var want, got any
+		if err := json.Unmarshal(original.files[fileName], &want); err != nil {
+			t.Fatal(err)
+		}
+		if err := json.Unmarshal(rp.files[fileName], &got); err != nil {
+			t.Fatalf("%s: watermarked file is not valid JSON: %v", fileName, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: watermarked file loads differently", fileName)
+		}
+	}
+	if watermarkable("ui/hud_screen.json") || watermarkable("animations/test.json") || !watermarkable("subpacks/low/models/entity/bones.json") {
+		t.Fatal("unexpected watermarkable files")
+	}
+}